@@ -0,0 +1,135 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 19:02:36
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 19:02:36
+ * @Description:
+ */
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// Backend is a key/value store shared by several processes, such as redis.
+	Backend interface {
+		// Get returns the value of key, or ErrNotFound if key does not exist.
+		Get(ctx context.Context, key string) ([]byte, error)
+		// MGet returns the values of the existing keys, the missing keys are
+		// absent from the result.
+		MGet(ctx context.Context, keys ...string) (map[string][]byte, error)
+		// Set sets the value of key, a ttl less than or equal to 0 means never expire.
+		Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+		// Delete deletes the keys, the missing keys are ignored.
+		Delete(ctx context.Context, keys ...string) error
+	}
+
+	// Invalidation tells that the keys were changed in the backend by Source.
+	Invalidation struct {
+		Source string
+		Keys   []string
+	}
+
+	// Invalidator broadcasts the invalidations to all the processes sharing a backend.
+	Invalidator interface {
+		Publish(ctx context.Context, msg Invalidation) error
+		// Subscribe calls fn with every published invalidation, until cancel is called.
+		Subscribe(fn func(msg Invalidation)) (cancel func())
+	}
+
+	// MemoryBackend is a Backend kept in memory, it's meant for tests and
+	// as a reference for the implementations.
+	MemoryBackend struct {
+		store *LRU[string, []byte]
+	}
+
+	// LocalInvalidator is an Invalidator delivering the invalidations within
+	// the process, it's meant for tests and as a reference for the implementations.
+	LocalInvalidator struct {
+		lock sync.RWMutex
+		seq  int
+		subs map[int]func(msg Invalidation)
+	}
+)
+
+// NewMemoryBackend returns a MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		store: NewLRU[string, []byte](0),
+	}
+}
+
+func (b *MemoryBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	v, ok := b.store.Get(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyBytes(v), nil
+}
+
+func (b *MemoryBackend) MGet(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if v, ok := b.store.Get(key); ok {
+			values[key] = copyBytes(v)
+		}
+	}
+	return values, nil
+}
+
+func (b *MemoryBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	b.store.PutWithTTL(key, copyBytes(value), ttl)
+	return nil
+}
+
+func (b *MemoryBackend) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		b.store.Remove(key)
+	}
+	return nil
+}
+
+// NewLocalInvalidator returns a LocalInvalidator.
+func NewLocalInvalidator() *LocalInvalidator {
+	return &LocalInvalidator{
+		subs: make(map[int]func(msg Invalidation)),
+	}
+}
+
+func (i *LocalInvalidator) Publish(ctx context.Context, msg Invalidation) error {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	for _, fn := range i.subs {
+		fn(msg)
+	}
+	return nil
+}
+
+func (i *LocalInvalidator) Subscribe(fn func(msg Invalidation)) (cancel func()) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.seq++
+	id := i.seq
+	i.subs[id] = fn
+
+	return func() {
+		i.lock.Lock()
+		defer i.lock.Unlock()
+		delete(i.subs, id)
+	}
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
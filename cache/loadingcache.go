@@ -0,0 +1,247 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 17:20:44
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 22:10:26
+ * @Description:
+ */
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cnzf1/gocore/thread"
+	"go.uber.org/atomic"
+)
+
+// ErrNotFound can be returned by a Loader to tell that the key does not exist,
+// like any other error it's cached for the negative ttl.
+var ErrNotFound = errors.New("cache: not found")
+
+type (
+	// Loader loads the value of key on a cache miss.
+	Loader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+	// LoadingCache is a LRU which loads the missing values by itself,
+	// the concurrent loads of the same key are deduplicated.
+	LoadingCache[K comparable, V any] struct {
+		cache        *LRU[K, *loadEntry[V]]
+		lock         sync.Mutex
+		calls        map[K]*loadCall[V]
+		refreshAfter time.Duration
+		negativeTTL  time.Duration
+	}
+
+	loadEntry[V any] struct {
+		value      V
+		err        error
+		refreshAt  time.Time
+		refreshing atomic.Bool
+	}
+
+	// loadCall is a load in flight, shared by the goroutines missing the same key.
+	loadCall[V any] struct {
+		done  chan struct{}
+		value V
+		err   error
+	}
+)
+
+// WithRefreshAfter makes a LoadingCache serve the values loaded more than d ago
+// while one goroutine reloads them in the background. The values are still
+// removed when the ttl set by WithTTL is reached.
+func WithRefreshAfter(d time.Duration) Option {
+	return func(c *config) {
+		c.refreshAfter = d
+	}
+}
+
+// WithNegativeTTL makes a LoadingCache cache the errors returned by the loader
+// for ttl, without it the errors are not cached.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.negativeTTL = ttl
+	}
+}
+
+// NewLoadingCache returns a LoadingCache holding at most capacity entries,
+// it accepts the same options as NewLRU.
+func NewLoadingCache[K comparable, V any](capacity int, opts ...Option) *LoadingCache[K, V] {
+	cfg := newConfig(opts...)
+
+	// the callbacks are given the loaded values, not the internal entries.
+	if fn, ok := cfg.onEvict.(func(K, V, EvictReason)); ok {
+		cfg.onEvict = func(key K, e *loadEntry[V], reason EvictReason) {
+			if e.err == nil {
+				fn(key, e.value, reason)
+			}
+		}
+	}
+	if fn, ok := cfg.sizer.(func(V) int64); ok {
+		cfg.sizer = func(e *loadEntry[V]) int64 {
+			if e.err != nil {
+				return 1
+			}
+			return fn(e.value)
+		}
+	}
+
+	return &LoadingCache[K, V]{
+		cache:        newLRU[K, *loadEntry[V]](capacity, cfg),
+		calls:        make(map[K]*loadCall[V]),
+		refreshAfter: cfg.refreshAfter,
+		negativeTTL:  cfg.negativeTTL,
+	}
+}
+
+// GetOrLoad returns the value of key, it's loaded by loader on a cache miss.
+// When several goroutines miss the same key, only one of them calls loader
+// with its own ctx, and the others share the result. If the load fails because
+// that ctx is done, the others load again with their own.
+func (c *LoadingCache[K, V]) GetOrLoad(ctx context.Context, key K, loader Loader[K, V]) (V, error) {
+	if e, ok := c.cache.Get(key); ok {
+		if e.err == nil && c.stale(e) && e.refreshing.CompareAndSwap(false, true) {
+			thread.GoSafe(func() {
+				c.refresh(key, e, loader)
+			})
+		}
+		return e.value, e.err
+	}
+
+	return c.do(ctx, key, func() (V, error) {
+		// the key may have been loaded while waiting for the flight.
+		if e, ok := c.cache.Peek(key); ok {
+			return e.value, e.err
+		}
+		return c.load(ctx, key, loader)
+	})
+}
+
+// Get returns the value of key without loading it.
+func (c *LoadingCache[K, V]) Get(key K) (value V, ok bool) {
+	e, ok := c.cache.Get(key)
+	if !ok || e.err != nil {
+		return value, false
+	}
+	return e.value, true
+}
+
+// Put sets the value of key, as if it was loaded.
+func (c *LoadingCache[K, V]) Put(key K, value V) bool {
+	return c.cache.Put(key, c.newEntry(value))
+}
+
+// Remove deletes key, the next GetOrLoad loads it again.
+func (c *LoadingCache[K, V]) Remove(key K) bool {
+	return c.cache.Remove(key)
+}
+
+// Len returns the number of entries, including the cached errors.
+func (c *LoadingCache[K, V]) Len() int {
+	return c.cache.Len()
+}
+
+// Purge removes all the entries.
+func (c *LoadingCache[K, V]) Purge() {
+	c.cache.Purge()
+}
+
+// Close stops the cleanup goroutine, if any.
+func (c *LoadingCache[K, V]) Close() {
+	c.cache.Close()
+}
+
+// Stats returns the counters of the cache, including the loads.
+func (c *LoadingCache[K, V]) Stats() Stats {
+	return c.cache.Stats()
+}
+
+func (c *LoadingCache[K, V]) load(ctx context.Context, key K, loader Loader[K, V]) (V, error) {
+	start := time.Now()
+	v, err := loader(ctx, key)
+	c.cache.recordLoad(time.Since(start), err)
+	if err != nil {
+		// the errors of the context are specific to the caller, never cache them.
+		if c.negativeTTL > 0 && !isContextError(err) {
+			c.cache.PutWithTTL(key, &loadEntry[V]{err: err}, c.negativeTTL)
+		}
+		return v, err
+	}
+
+	c.cache.Put(key, c.newEntry(v))
+	return v, nil
+}
+
+// refresh reloads the stale entry e in the background, e keeps being served
+// until the reload succeeds.
+func (c *LoadingCache[K, V]) refresh(key K, e *loadEntry[V], loader Loader[K, V]) {
+	defer e.refreshing.Store(false)
+
+	c.do(context.Background(), key, func() (V, error) {
+		start := time.Now()
+		v, err := loader(context.Background(), key)
+		c.cache.recordLoad(time.Since(start), err)
+		if err != nil {
+			return v, err
+		}
+
+		c.cache.Put(key, c.newEntry(v))
+		return v, nil
+	})
+}
+
+// do calls fn unless a call of key is in flight, in which case it waits for
+// the result of that call, or for ctx to be done.
+func (c *LoadingCache[K, V]) do(ctx context.Context, key K, fn func() (V, error)) (V, error) {
+	for {
+		c.lock.Lock()
+		call, ok := c.calls[key]
+		if !ok {
+			call = &loadCall[V]{done: make(chan struct{})}
+			c.calls[key] = call
+			c.lock.Unlock()
+
+			defer func() {
+				c.lock.Lock()
+				delete(c.calls, key)
+				c.lock.Unlock()
+				close(call.done)
+			}()
+
+			call.value, call.err = fn()
+			return call.value, call.err
+		}
+		c.lock.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+
+		// the context of the caller which loaded isn't the one of this caller.
+		if !isContextError(call.err) || ctx.Err() != nil {
+			return call.value, call.err
+		}
+	}
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func (c *LoadingCache[K, V]) newEntry(value V) *loadEntry[V] {
+	e := &loadEntry[V]{value: value}
+	if c.refreshAfter > 0 {
+		e.refreshAt = time.Now().Add(c.refreshAfter)
+	}
+	return e
+}
+
+func (c *LoadingCache[K, V]) stale(e *loadEntry[V]) bool {
+	return !e.refreshAt.IsZero() && time.Now().After(e.refreshAt)
+}
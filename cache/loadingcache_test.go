@@ -0,0 +1,169 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cnzf1/gocore/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadingCacheGetOrLoad(t *testing.T) {
+	c := cache.NewLoadingCache[string, int](10)
+	var calls int32
+	loader := func(ctx context.Context, key string) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return len(key), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), "foo", loader)
+			assert.Nil(t, err)
+			assert.Equal(t, 3, v)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	v, ok := c.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, 3, v)
+}
+
+func TestLoadingCacheNegative(t *testing.T) {
+	c := cache.NewLoadingCache[string, int](10, cache.WithNegativeTTL(50*time.Millisecond))
+	var calls int32
+	loader := func(ctx context.Context, key string) (int, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return 0, cache.ErrNotFound
+		}
+		return 1, nil
+	}
+
+	_, err := c.GetOrLoad(context.Background(), "foo", loader)
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+	_, err = c.GetOrLoad(context.Background(), "foo", loader)
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+	_, ok := c.Get("foo")
+	assert.False(t, ok)
+
+	time.Sleep(100 * time.Millisecond)
+	v, err := c.GetOrLoad(context.Background(), "foo", loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestLoadingCacheContextError(t *testing.T) {
+	c := cache.NewLoadingCache[string, int](10, cache.WithNegativeTTL(time.Minute))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	loader := func(ctx context.Context, key string) (int, error) {
+		return 0, ctx.Err()
+	}
+	_, err := c.GetOrLoad(ctx, "foo", loader)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestLoadingCacheDistinctKeys(t *testing.T) {
+	// the keys are different pointers to equal values.
+	c := cache.NewLoadingCache[*int, *int](10)
+	k1, k2 := new(int), new(int)
+	loader := func(ctx context.Context, key *int) (*int, error) {
+		time.Sleep(50 * time.Millisecond)
+		return key, nil
+	}
+
+	var wg sync.WaitGroup
+	for _, key := range []*int{k1, k2} {
+		key := key
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), key, loader)
+			assert.Nil(t, err)
+			assert.True(t, v == key)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLoadingCacheLeaderCanceled(t *testing.T) {
+	c := cache.NewLoadingCache[string, int](10)
+	started := make(chan struct{}, 2)
+	loader := func(ctx context.Context, key string) (int, error) {
+		started <- struct{}{}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+			return 1, nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := c.GetOrLoad(ctx, "foo", loader)
+		leader <- err
+	}()
+	<-started
+
+	waiter := make(chan int)
+	go func() {
+		v, err := c.GetOrLoad(context.Background(), "foo", loader)
+		assert.Nil(t, err)
+		waiter <- v
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	// the waiter loads again with its own context.
+	assert.Equal(t, context.Canceled, <-leader)
+	assert.Equal(t, 1, <-waiter)
+}
+
+func TestLoadingCacheRefresh(t *testing.T) {
+	c := cache.NewLoadingCache[string, int](10, cache.WithRefreshAfter(20*time.Millisecond))
+	var version int32
+	loader := func(ctx context.Context, key string) (int, error) {
+		return int(atomic.AddInt32(&version, 1)), nil
+	}
+
+	v, err := c.GetOrLoad(context.Background(), "foo", loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	time.Sleep(50 * time.Millisecond)
+	// the stale value is served while it's reloaded in the background.
+	v, err = c.GetOrLoad(context.Background(), "foo", loader)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	assert.Eventually(t, func() bool {
+		v, _ := c.Get("foo")
+		return v == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestLoadingCacheOnEvict(t *testing.T) {
+	var evicted []string
+	c := cache.NewLoadingCache[string, int](1, cache.WithOnEvict(func(key string, value int, reason cache.EvictReason) {
+		evicted = append(evicted, key)
+	}))
+	c.Put("a", 1)
+	c.Put("b", 2)
+	assert.Equal(t, []string{"a"}, evicted)
+}
@@ -0,0 +1,565 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 10:12:31
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 22:10:26
+ * @Description:
+ */
+package cache
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cnzf1/gocore/hash"
+	"github.com/cnzf1/gocore/lang"
+	"github.com/cnzf1/gocore/thread"
+)
+
+const (
+	defaultShards      = 16
+	minEntriesPerShard = 64
+)
+
+// EvictReason tells why an entry left the cache.
+type EvictReason int
+
+const (
+	// EvictCapacity means the entry was pushed out by a newer one.
+	EvictCapacity EvictReason = iota
+	// EvictExpired means the ttl of the entry was reached.
+	EvictExpired
+	// EvictRemoved means the entry was removed by Remove or Purge.
+	EvictRemoved
+	// EvictReplaced means the value was overwritten by Put.
+	EvictReplaced
+
+	evictReasons = iota
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictExpired:
+		return "expired"
+	case EvictRemoved:
+		return "removed"
+	case EvictReplaced:
+		return "replaced"
+	default:
+		return "EvictReason(" + strconv.Itoa(int(r)) + ")"
+	}
+}
+
+// Policy decides which entry a full shard evicts.
+type Policy int
+
+const (
+	// PolicyLRU evicts the least recently used entry.
+	PolicyLRU Policy = iota
+	// PolicyTinyLFU is W-TinyLFU, it admits an entry into the main space only
+	// if it's used more often than the entry it would evict, which makes the
+	// cache resistant to one-off scans.
+	PolicyTinyLFU
+)
+
+type (
+	// LRU is a concurrency safe LRU cache, the keys are spread over several
+	// shards to reduce lock contention, each shard evicts on its own.
+	// The eviction policy can be changed by WithPolicy.
+	LRU[K comparable, V any] struct {
+		shards  []*lruShard[K, V]
+		mask    uint64
+		hash    func(K) uint64
+		ttl     time.Duration
+		sizer   func(V) int64
+		codec   Codec[V]
+		onEvict func(key K, value V, reason EvictReason)
+		hook    StatsHook
+		stats   statsCounter
+		stop    chan lang.PlaceholderType
+		once    sync.Once
+	}
+
+	lruShard[K comparable, V any] struct {
+		lock sync.Mutex
+		engine[K, V]
+	}
+
+	// engine is the storage and eviction policy of a shard, it's not concurrent safe.
+	engine[K comparable, V any] interface {
+		Get(key K) (V, bool)
+		Peek(key K) (V, bool)
+		Put(key K, value V, cost int64, expire time.Time) bool
+		Remove(key K) bool
+		RemoveExpired(now time.Time)
+		Len() int
+		Cost() int64
+		Keys() []K
+		Purge()
+		// Range calls fn with the entries from the least to the most recently used.
+		Range(fn func(node *cacheNode[K, V]))
+		// drain returns the entries evicted since the last call.
+		drain() []eviction[K, V]
+		// evictedCounts returns the number of evicted entries by reason.
+		evictedCounts() [evictReasons]uint64
+	}
+
+	config struct {
+		shards  int
+		policy  Policy
+		hasher  any
+		ttl     time.Duration
+		tick    time.Duration
+		maxCost int64
+		sizer   any
+		onEvict any
+		codec   any
+		hook    StatsHook
+		// used by LoadingCache only.
+		refreshAfter time.Duration
+		negativeTTL  time.Duration
+	}
+
+	// Option customizes the caches created by NewLRU.
+	Option func(*config)
+)
+
+// WithShards sets the number of shards, it's rounded up to a power of 2.
+func WithShards(n int) Option {
+	return func(c *config) {
+		c.shards = n
+	}
+}
+
+// WithPolicy sets the eviction policy, PolicyLRU by default.
+func WithPolicy(p Policy) Option {
+	return func(c *config) {
+		c.policy = p
+	}
+}
+
+// WithHasher sets the function used to spread the keys over the shards, and to
+// count the keys of PolicyTinyLFU. The keys which aren't of a basic type, such
+// as the structs and the pointers, need it unless the cache has a single shard
+// and PolicyLRU. The equal keys must get the same hash.
+func WithHasher[K comparable](fn func(K) uint64) Option {
+	return func(c *config) {
+		c.hasher = fn
+	}
+}
+
+// WithTTL sets the default ttl of the entries added by Put.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.ttl = ttl
+	}
+}
+
+// WithCleanupInterval starts a goroutine removing the expired entries every tick,
+// without it expired entries are only removed when they are accessed or pushed out.
+// Call Close to stop the goroutine.
+func WithCleanupInterval(tick time.Duration) Option {
+	return func(c *config) {
+		c.tick = tick
+	}
+}
+
+// WithOnEvict sets a callback called whenever an entry leaves the cache.
+// It's called after the shard is unlocked, so it's safe to access the cache in it.
+func WithOnEvict[K comparable, V any](fn func(key K, value V, reason EvictReason)) Option {
+	return func(c *config) {
+		c.onEvict = fn
+	}
+}
+
+// WithMaxCost bounds the total cost of the entries instead of, or in addition to,
+// their number, an entry costing more than maxCost is rejected.
+// The cache has a single shard by default in this mode, if WithShards is given
+// the budget is split over the shards and so is the largest acceptable cost.
+func WithMaxCost(maxCost int64) Option {
+	return func(c *config) {
+		c.maxCost = maxCost
+	}
+}
+
+// WithSizer sets the function computing the cost of the values added by Put
+// and PutWithTTL, without it every value costs 1.
+func WithSizer[V any](fn func(V) int64) Option {
+	return func(c *config) {
+		c.sizer = fn
+	}
+}
+
+// NewLRU returns a LRU holding at most capacity entries,
+// a capacity less than or equal to 0 means no limit.
+func NewLRU[K comparable, V any](capacity int, opts ...Option) *LRU[K, V] {
+	return newLRU[K, V](capacity, newConfig(opts...))
+}
+
+func newConfig(opts ...Option) *config {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+func newLRU[K comparable, V any](capacity int, cfg *config) *LRU[K, V] {
+	shards := cfg.shards
+	if shards <= 0 && cfg.maxCost > 0 {
+		shards = 1
+	} else if shards <= 0 {
+		shards = defaultShards
+		for shards > 1 && capacity > 0 && capacity/shards < minEntriesPerShard {
+			shards >>= 1
+		}
+	}
+	shards = nextPowerOfTwo(shards)
+	for shards > 1 && capacity > 0 && shards > capacity {
+		shards >>= 1
+	}
+
+	c := &LRU[K, V]{
+		shards: make([]*lruShard[K, V], shards),
+		mask:   uint64(shards - 1),
+		hash:   hashBasicKey[K],
+		ttl:    cfg.ttl,
+		hook:   cfg.hook,
+		stop:   make(chan lang.PlaceholderType),
+	}
+	if cfg.hasher != nil {
+		fn, ok := cfg.hasher.(func(K) uint64)
+		if !ok {
+			panic(fmt.Sprintf("cache: hasher %T does not match key type", cfg.hasher))
+		}
+		c.hash = fn
+	} else if _, ok := hashKey(*new(K)); !ok {
+		if shards > 1 || cfg.policy == PolicyTinyLFU {
+			panic(fmt.Sprintf("cache: key type %T needs WithHasher", *new(K)))
+		}
+		// a single shard of PolicyLRU never hashes the keys.
+		c.hash = func(K) uint64 { return 0 }
+	}
+	if cfg.onEvict != nil {
+		fn, ok := cfg.onEvict.(func(K, V, EvictReason))
+		if !ok {
+			panic(fmt.Sprintf("cache: evict callback %T does not match cache type", cfg.onEvict))
+		}
+		c.onEvict = fn
+	}
+	if cfg.sizer != nil {
+		fn, ok := cfg.sizer.(func(V) int64)
+		if !ok {
+			panic(fmt.Sprintf("cache: sizer %T does not match value type", cfg.sizer))
+		}
+		c.sizer = fn
+	}
+
+	c.codec = JSONCodec[V]{}
+	if cfg.codec != nil {
+		codec, ok := cfg.codec.(Codec[V])
+		if !ok {
+			panic(fmt.Sprintf("cache: codec %T does not match value type", cfg.codec))
+		}
+		c.codec = codec
+	}
+
+	notify := c.onEvict != nil || c.hook != nil
+	for i := range c.shards {
+		shardCap, shardMaxCost := shardCapacity(capacity, shards, i), shardCost(cfg.maxCost, shards, i)
+		s := &lruShard[K, V]{}
+		switch cfg.policy {
+		case PolicyTinyLFU:
+			s.engine = newTinyLFUCache[K, V](shardCap, shardMaxCost, c.hash, notify)
+		default:
+			s.engine = newLRUCache[K, V](shardCap, shardMaxCost, notify)
+		}
+		c.shards[i] = s
+	}
+
+	if cfg.tick > 0 {
+		c.cleanup(cfg.tick)
+	}
+	return c
+}
+
+// Get returns the value of key and marks it as the most recently used.
+func (c *LRU[K, V]) Get(key K) (value V, ok bool) {
+	c.withShard(key, func(s engine[K, V]) {
+		value, ok = s.Get(key)
+	})
+	c.recordAccess(ok)
+	return
+}
+
+// Peek returns the value of key without updating its recency.
+func (c *LRU[K, V]) Peek(key K) (value V, ok bool) {
+	c.withShard(key, func(s engine[K, V]) {
+		value, ok = s.Peek(key)
+	})
+	return
+}
+
+// Put adds or updates the value of key with the default ttl, and evicts
+// the least recently used entries of the shard if it's full.
+// It returns false if the value costs more than the budget of the shard.
+func (c *LRU[K, V]) Put(key K, value V) bool {
+	return c.PutWithTTL(key, value, c.ttl)
+}
+
+// PutWithTTL is like Put, but the entry expires after ttl,
+// a ttl less than or equal to 0 means never expire.
+func (c *LRU[K, V]) PutWithTTL(key K, value V, ttl time.Duration) bool {
+	return c.PutWithCost(key, value, c.costOf(value), ttl)
+}
+
+// PutWithCost is like PutWithTTL, but with the given cost instead of the
+// one computed by the sizer.
+func (c *LRU[K, V]) PutWithCost(key K, value V, cost int64, ttl time.Duration) (ok bool) {
+	var expire time.Time
+	if ttl > 0 {
+		expire = time.Now().Add(ttl)
+	}
+	if cost < 0 {
+		cost = 0
+	}
+
+	c.withShard(key, func(s engine[K, V]) {
+		ok = s.Put(key, value, cost, expire)
+	})
+	return
+}
+
+// Remove deletes key from the cache, it returns false if key is not present.
+func (c *LRU[K, V]) Remove(key K) (ok bool) {
+	c.withShard(key, func(s engine[K, V]) {
+		ok = s.Remove(key)
+	})
+	return
+}
+
+// Len returns the number of entries in the cache, including the expired
+// entries not removed yet.
+func (c *LRU[K, V]) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.lock.Lock()
+		n += s.Len()
+		s.lock.Unlock()
+	}
+	return n
+}
+
+// Cost returns the total cost of the entries in the cache.
+func (c *LRU[K, V]) Cost() int64 {
+	var n int64
+	for _, s := range c.shards {
+		s.lock.Lock()
+		n += s.Cost()
+		s.lock.Unlock()
+	}
+	return n
+}
+
+// Keys returns all the keys in the cache, with PolicyLRU they are ordered
+// from the most to the least recently used within each shard.
+func (c *LRU[K, V]) Keys() []K {
+	var keys []K
+	for _, s := range c.shards {
+		s.lock.Lock()
+		keys = append(keys, s.Keys()...)
+		s.lock.Unlock()
+	}
+	return keys
+}
+
+// Purge removes all the entries from the cache.
+func (c *LRU[K, V]) Purge() {
+	for _, s := range c.shards {
+		s.lock.Lock()
+		s.Purge()
+		evicted := s.drain()
+		s.lock.Unlock()
+		c.notify(evicted)
+	}
+}
+
+// RemoveExpired removes all the expired entries from the cache.
+func (c *LRU[K, V]) RemoveExpired() {
+	now := time.Now()
+	for _, s := range c.shards {
+		s.lock.Lock()
+		s.RemoveExpired(now)
+		evicted := s.drain()
+		s.lock.Unlock()
+		c.notify(evicted)
+	}
+}
+
+// Close stops the cleanup goroutine, if any.
+func (c *LRU[K, V]) Close() {
+	c.once.Do(func() {
+		close(c.stop)
+	})
+}
+
+func (c *LRU[K, V]) cleanup(tick time.Duration) {
+	thread.GoSafe(func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.RemoveExpired()
+			case <-c.stop:
+				return
+			}
+		}
+	})
+}
+
+func (c *LRU[K, V]) costOf(value V) int64 {
+	if c.sizer == nil {
+		return 1
+	}
+	return c.sizer(value)
+}
+
+func (c *LRU[K, V]) shard(key K) *lruShard[K, V] {
+	return c.shards[c.hash(key)&c.mask]
+}
+
+// withShard runs fn with the shard of key locked, and notifies the evicted entries
+// after the shard is unlocked.
+func (c *LRU[K, V]) withShard(key K, fn func(s engine[K, V])) {
+	s := c.shard(key)
+	s.lock.Lock()
+	fn(s.engine)
+	evicted := s.drain()
+	s.lock.Unlock()
+	c.notify(evicted)
+}
+
+func (c *LRU[K, V]) notify(evicted []eviction[K, V]) {
+	for _, e := range evicted {
+		if c.hook != nil {
+			c.hook.OnEvict(e.reason)
+		}
+		if c.onEvict != nil {
+			c.onEvict(e.key, e.value, e.reason)
+		}
+	}
+}
+
+// shardCapacity splits capacity over the shards, the first shards take the remainder.
+func shardCapacity(capacity, shards, idx int) int {
+	if capacity <= 0 {
+		return 0
+	}
+
+	n := capacity / shards
+	if idx < capacity%shards {
+		n++
+	}
+	return n
+}
+
+// shardCost splits maxCost over the shards, the first shards take the remainder.
+func shardCost(maxCost int64, shards, idx int) int64 {
+	if maxCost <= 0 {
+		return 0
+	}
+
+	n := maxCost / int64(shards)
+	if int64(idx) < maxCost%int64(shards) {
+		n++
+	}
+	return n
+}
+
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// hashBasicKey is the hasher of the keys of a basic type.
+func hashBasicKey[K comparable](key K) uint64 {
+	h, _ := hashKey(key)
+	return h
+}
+
+// hashKey hashes the keys of a basic type, false for the other types.
+func hashKey[K comparable](key K) (uint64, bool) {
+	switch k := any(key).(type) {
+	case string:
+		return hash.Hash([]byte(k)), true
+	case bool:
+		if k {
+			return mix64(1), true
+		}
+		return mix64(0), true
+	case int:
+		return mix64(uint64(k)), true
+	case int8:
+		return mix64(uint64(k)), true
+	case int16:
+		return mix64(uint64(k)), true
+	case int32:
+		return mix64(uint64(k)), true
+	case int64:
+		return mix64(uint64(k)), true
+	case uint:
+		return mix64(uint64(k)), true
+	case uint8:
+		return mix64(uint64(k)), true
+	case uint16:
+		return mix64(uint64(k)), true
+	case uint32:
+		return mix64(uint64(k)), true
+	case uint64:
+		return mix64(k), true
+	case uintptr:
+		return mix64(uint64(k)), true
+	case float32:
+		return hashFloat(float64(k)), true
+	case float64:
+		return hashFloat(k), true
+	case complex64:
+		return hashComplex(complex128(k)), true
+	case complex128:
+		return hashComplex(k), true
+	default:
+		return 0, false
+	}
+}
+
+// hashFloat hashes the bits of f, -0 as 0 since they are equal keys.
+func hashFloat(f float64) uint64 {
+	if f == 0 {
+		f = 0
+	}
+	return mix64(math.Float64bits(f))
+}
+
+func hashComplex(c complex128) uint64 {
+	return hashFloat(real(c)) ^ bits.RotateLeft64(hashFloat(imag(c)), 32)
+}
+
+// mix64 is the finalizer of splitmix64, it spreads sequential integers.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,263 @@
+package cache_test
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cnzf1/gocore/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	c := cache.NewLRU[string, int](2, cache.WithShards(1))
+	c.Put("a", 1)
+	c.Put("b", 2)
+
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	c.Put("c", 3)
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"c", "a"}, c.Keys())
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLRUPeek(t *testing.T) {
+	c := cache.NewLRU[string, int](2, cache.WithShards(1))
+	c.Put("a", 1)
+	c.Put("b", 2)
+
+	v, ok := c.Peek("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	c.Put("c", 3)
+	_, ok = c.Peek("a")
+	assert.False(t, ok)
+}
+
+func TestLRURemoveAndPurge(t *testing.T) {
+	c := cache.NewLRU[int, string](100)
+	for i := 0; i < 10; i++ {
+		c.Put(i, strconv.Itoa(i))
+	}
+
+	assert.True(t, c.Remove(3))
+	assert.False(t, c.Remove(3))
+	assert.Equal(t, 9, c.Len())
+
+	c.Purge()
+	assert.Equal(t, 0, c.Len())
+	assert.Empty(t, c.Keys())
+}
+
+func TestLRUCapacity(t *testing.T) {
+	c := cache.NewLRU[int, int](1000, cache.WithShards(8))
+	for i := 0; i < 10000; i++ {
+		c.Put(i, i)
+	}
+	assert.Equal(t, 1000, c.Len())
+}
+
+func TestLRUWithHasher(t *testing.T) {
+	c := cache.NewLRU[int, int](4, cache.WithShards(4), cache.WithHasher(func(k int) uint64 {
+		return 0
+	}))
+	for i := 0; i < 4; i++ {
+		c.Put(i, i)
+	}
+
+	// all keys land in the first shard, which holds a single entry.
+	assert.Equal(t, 1, c.Len())
+	assert.Panics(t, func() {
+		cache.NewLRU[string, int](4, cache.WithHasher(func(k int) uint64 { return 0 }))
+	})
+}
+
+func TestLRUConcurrent(t *testing.T) {
+	c := cache.NewLRU[string, int](1024)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(base int) {
+			defer wg.Done()
+			for j := 0; j < 10000; j++ {
+				key := strconv.Itoa(base*10000 + j)
+				c.Put(key, j)
+				c.Get(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.True(t, c.Len() <= 1024)
+}
+
+func TestLRUTTL(t *testing.T) {
+	c := cache.NewLRU[string, int](10, cache.WithTTL(50*time.Millisecond))
+	c.Put("a", 1)
+	c.PutWithTTL("b", 2, 0)
+	c.PutWithTTL("c", 3, time.Hour)
+
+	time.Sleep(100 * time.Millisecond)
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	_, ok = c.Peek("b")
+	assert.True(t, ok)
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLRUCleanup(t *testing.T) {
+	var lock sync.Mutex
+	reasons := make(map[string]cache.EvictReason)
+	c := cache.NewLRU[string, int](10,
+		cache.WithTTL(20*time.Millisecond),
+		cache.WithCleanupInterval(10*time.Millisecond),
+		cache.WithOnEvict(func(key string, value int, reason cache.EvictReason) {
+			lock.Lock()
+			reasons[key] = reason
+			lock.Unlock()
+		}))
+	defer c.Close()
+
+	c.Put("a", 1)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, c.Len())
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, cache.EvictExpired, reasons["a"])
+}
+
+func TestLRUOnEvict(t *testing.T) {
+	type evicted struct {
+		key    string
+		value  int
+		reason cache.EvictReason
+	}
+
+	var got []evicted
+	c := cache.NewLRU[string, int](2, cache.WithShards(1),
+		cache.WithOnEvict(func(key string, value int, reason cache.EvictReason) {
+			got = append(got, evicted{key, value, reason})
+		}))
+
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("a", 10)
+	c.Put("c", 3)
+	c.Remove("a")
+	c.Purge()
+
+	assert.Equal(t, []evicted{
+		{"a", 1, cache.EvictReplaced},
+		{"b", 2, cache.EvictCapacity},
+		{"a", 10, cache.EvictRemoved},
+		{"c", 3, cache.EvictRemoved},
+	}, got)
+	assert.Equal(t, "capacity", cache.EvictCapacity.String())
+}
+
+func TestLRUCache(t *testing.T) {
+	c := cache.NewLRUCache(2)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	assert.Equal(t, 1, c.Get("a"))
+
+	c.Put("c", 3)
+	assert.Equal(t, -1, c.Get("b"))
+	assert.Equal(t, 1, c.Get("a"))
+	assert.Equal(t, 3, c.Get("c"))
+}
+
+func TestLRUMaxCostUpdate(t *testing.T) {
+	var got []cache.EvictReason
+	c := cache.NewLRU[string, string](0, cache.WithShards(1), cache.WithMaxCost(10),
+		cache.WithSizer(func(v string) int64 {
+			return int64(len(v))
+		}),
+		cache.WithOnEvict(func(key string, value string, reason cache.EvictReason) {
+			got = append(got, reason)
+		}))
+
+	assert.True(t, c.Put("a", "aaaa"))
+	// the update over the budget drops the stale value.
+	assert.False(t, c.Put("a", "aaaaaaaaaaa"))
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, int64(0), c.Cost())
+	assert.Equal(t, []cache.EvictReason{cache.EvictReplaced}, got)
+}
+
+func TestLRUMaxCost(t *testing.T) {
+	c := cache.NewLRU[string, string](0, cache.WithMaxCost(10),
+		cache.WithSizer(func(v string) int64 {
+			return int64(len(v))
+		}))
+
+	assert.True(t, c.Put("a", "aaaa"))
+	assert.True(t, c.Put("b", "bbbb"))
+	assert.Equal(t, int64(8), c.Cost())
+
+	assert.True(t, c.Put("c", "cccc"))
+	_, ok := c.Peek("a")
+	assert.False(t, ok)
+	assert.Equal(t, int64(8), c.Cost())
+
+	assert.False(t, c.Put("d", "ddddddddddd"))
+	assert.True(t, c.PutWithCost("d", "d", 10, 0))
+	assert.Equal(t, []string{"d"}, c.Keys())
+	assert.Equal(t, int64(10), c.Cost())
+
+	assert.True(t, c.Put("d", "dd"))
+	assert.Equal(t, int64(2), c.Cost())
+	c.Remove("d")
+	assert.Equal(t, int64(0), c.Cost())
+}
+
+func TestLRUFloatKeys(t *testing.T) {
+	c := cache.NewLRU[float64, int](1024, cache.WithShards(16))
+	negZero := math.Copysign(0, -1)
+	c.Put(0.0, 1)
+	v, ok := c.Get(negZero)
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	c.Put(negZero, 2)
+	assert.Equal(t, 1, c.Len())
+
+	cc := cache.NewLRU[complex128, int](1024, cache.WithShards(16))
+	cc.Put(complex(0, 1), 1)
+	_, ok = cc.Get(complex(negZero, 1))
+	assert.True(t, ok)
+}
+
+func TestLRUKeyHasher(t *testing.T) {
+	type point struct{ x, y int }
+
+	// the keys of a non basic type need a hasher, unless they are never hashed.
+	assert.Panics(t, func() {
+		cache.NewLRU[point, int](1024, cache.WithShards(16))
+	})
+	assert.Panics(t, func() {
+		cache.NewLRU[point, int](10, cache.WithShards(1), cache.WithPolicy(cache.PolicyTinyLFU))
+	})
+
+	c := cache.NewLRU[point, int](10, cache.WithShards(1))
+	c.Put(point{1, 2}, 1)
+	v, ok := c.Get(point{1, 2})
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	c = cache.NewLRU[point, int](1024, cache.WithShards(16), cache.WithHasher(func(p point) uint64 {
+		return uint64(p.x)<<32 | uint64(p.y)
+	}))
+	c.Put(point{1, 2}, 1)
+	_, ok = c.Get(point{1, 2})
+	assert.True(t, ok)
+}
@@ -2,97 +2,292 @@
  * @Author: cnzf1
  * @Date: 2023-03-12 19:38:39
  * @LastEditors: cnzf1
- * @LastEditTime: 2023-03-13 23:54:00
+ * @LastEditTime: 2026-10-17 22:10:26
  * @Description:
  */
 package cache
 
-import "github.com/cnzf1/gocore/lang"
+import (
+	"time"
 
+	"github.com/cnzf1/gocore/lang"
+)
+
+// LRUCache is a LRU cache of string keys, it's not concurrent safe.
+//
+// Deprecated: use LRU, which is generic and concurrent safe.
 type LRUCache struct {
+	cache *lruCache[string, lang.AnyType]
+}
+
+// NewLRUCache returns a LRUCache holding at most capacity entries.
+//
+// Deprecated: use NewLRU.
+func NewLRUCache(capacity int) LRUCache {
+	return LRUCache{
+		cache: newLRUCache[string, lang.AnyType](capacity, 0, false),
+	}
+}
+
+// Get returns the value of key, -1 if key is not present.
+func (this *LRUCache) Get(key string) lang.AnyType {
+	value, ok := this.cache.Get(key)
+	if !ok {
+		return -1
+	}
+	return value
+}
+
+// Put adds or updates the value of key.
+func (this *LRUCache) Put(key string, value lang.AnyType) {
+	this.cache.Put(key, value, 0, time.Time{})
+}
+
+// lruCache is a doubly linked list based LRU, it's not concurrent safe,
+// LRU guards every instance with the lock of its shard.
+type lruCache[K comparable, V any] struct {
 	size     int
 	capacity int
-	cache    map[string]*CacheNode
-	head     *CacheNode
-	tail     *CacheNode
+	cost     int64
+	maxCost  int64
+	cache    map[K]*cacheNode[K, V]
+	head     *cacheNode[K, V]
+	tail     *cacheNode[K, V]
+	evictions[K, V]
 }
 
-type CacheNode struct {
-	key        string
-	value      lang.AnyType
-	prev, next *CacheNode
+type cacheNode[K comparable, V any] struct {
+	key        K
+	value      V
+	cost       int64
+	expire     time.Time
+	prev, next *cacheNode[K, V]
+	// segment is the list holding the node in tinyLFUCache.
+	segment uint8
 }
 
-func NewLRUCache(capacity int) LRUCache {
-	l := LRUCache{
-		cache:    make(map[string]*CacheNode),
+type eviction[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+// evictions counts the entries evicted by an engine, and collects them
+// for the callbacks if notify is set.
+type evictions[K comparable, V any] struct {
+	notify  bool
+	evicted []eviction[K, V]
+	counts  [evictReasons]uint64
+}
+
+// newLRUCache returns a lruCache holding at most capacity entries, and whose
+// entries cost at most maxCost, a limit less than or equal to 0 means no limit.
+func newLRUCache[K comparable, V any](capacity int, maxCost int64, notify bool) *lruCache[K, V] {
+	var zeroK K
+	var zeroV V
+
+	l := &lruCache[K, V]{
+		cache:    make(map[K]*cacheNode[K, V]),
 		capacity: capacity,
-		head:     createCacheNode("", ""),
-		tail:     createCacheNode("", ""),
+		maxCost:  maxCost,
+		head:     createCacheNode(zeroK, zeroV, 0, time.Time{}),
+		tail:     createCacheNode(zeroK, zeroV, 0, time.Time{}),
 	}
+	l.notify = notify
 	l.head.next = l.tail
 	l.tail.prev = l.head
 	return l
 }
 
-func createCacheNode(key string, value lang.AnyType) *CacheNode {
-	return &CacheNode{
-		key:   key,
-		value: value,
+func createCacheNode[K comparable, V any](key K, value V, cost int64, expire time.Time) *cacheNode[K, V] {
+	return &cacheNode[K, V]{
+		key:    key,
+		value:  value,
+		cost:   cost,
+		expire: expire,
 	}
 }
 
-func (this *LRUCache) incrSize(inc int) {
+func (node *cacheNode[K, V]) expired(now time.Time) bool {
+	return !node.expire.IsZero() && now.After(node.expire)
+}
+
+func (this *lruCache[K, V]) incrSize(inc int, cost int64) {
 	this.size += inc
-	if this.size > this.capacity {
+	this.cost += cost
+	for inc > 0 && this.overflow() {
 		removed := this.removeTail()
 		delete(this.cache, removed.key)
+		this.evict(removed.key, removed.value, EvictCapacity)
 	}
 }
 
-func (this *LRUCache) addToHead(node *CacheNode) {
+func (this *lruCache[K, V]) overflow() bool {
+	return (this.capacity > 0 && this.size > this.capacity) ||
+		(this.maxCost > 0 && this.cost > this.maxCost)
+}
+
+func (this *lruCache[K, V]) addToHead(node *cacheNode[K, V]) {
 	node.prev = this.head
 	node.next = this.head.next
 	this.head.next.prev = node
 	this.head.next = node
-	this.incrSize(1)
+	this.incrSize(1, node.cost)
 }
 
-func (this *LRUCache) removeNode(node *CacheNode) {
+func (this *lruCache[K, V]) removeNode(node *cacheNode[K, V]) {
 	node.prev.next = node.next
 	node.next.prev = node.prev
-	this.incrSize(-1)
+	this.incrSize(-1, -node.cost)
 }
 
-func (this *LRUCache) moveToHead(node *CacheNode) {
+func (this *lruCache[K, V]) moveToHead(node *cacheNode[K, V]) {
 	this.removeNode(node)
 	this.addToHead(node)
 }
 
-func (this *LRUCache) removeTail() *CacheNode {
+func (this *lruCache[K, V]) removeTail() *cacheNode[K, V] {
 	node := this.tail.prev
 	this.removeNode(node)
 	return node
 }
 
-func (this *LRUCache) Get(key string) lang.AnyType {
-	if _, ok := this.cache[key]; !ok {
-		return -1
+func (this *lruCache[K, V]) deleteNode(node *cacheNode[K, V], reason EvictReason) {
+	delete(this.cache, node.key)
+	this.removeNode(node)
+	this.evict(node.key, node.value, reason)
+}
+
+func (e *evictions[K, V]) evict(key K, value V, reason EvictReason) {
+	e.counts[reason]++
+	if e.notify {
+		e.evicted = append(e.evicted, eviction[K, V]{key: key, value: value, reason: reason})
+	}
+}
+
+// drain returns the entries evicted since the last call.
+func (e *evictions[K, V]) drain() []eviction[K, V] {
+	evicted := e.evicted
+	e.evicted = nil
+	return evicted
+}
+
+// evictedCounts returns the number of evicted entries by reason.
+func (e *evictions[K, V]) evictedCounts() [evictReasons]uint64 {
+	return e.counts
+}
+
+func (this *lruCache[K, V]) Get(key K) (value V, ok bool) {
+	node, ok := this.cache[key]
+	if !ok {
+		return
+	}
+
+	if node.expired(time.Now()) {
+		this.deleteNode(node, EvictExpired)
+		return value, false
 	}
 
-	node := this.cache[key]
 	this.moveToHead(node)
-	return node.value
+	return node.value, true
 }
 
-func (this *LRUCache) Put(key string, value lang.AnyType) {
-	if _, ok := this.cache[key]; !ok {
-		node := createCacheNode(key, value)
+// Peek returns the value of key without updating its recency.
+func (this *lruCache[K, V]) Peek(key K) (value V, ok bool) {
+	node, ok := this.cache[key]
+	if !ok {
+		return
+	}
+
+	if node.expired(time.Now()) {
+		this.deleteNode(node, EvictExpired)
+		return value, false
+	}
+
+	return node.value, true
+}
+
+// Put adds or updates the value of key, a zero expire means never expire.
+// It returns false if cost exceeds maxCost, and the stale value of key is removed.
+func (this *lruCache[K, V]) Put(key K, value V, cost int64, expire time.Time) bool {
+	if this.maxCost > 0 && cost > this.maxCost {
+		if node, ok := this.cache[key]; ok {
+			this.deleteNode(node, EvictReplaced)
+		}
+		return false
+	}
+
+	if node, ok := this.cache[key]; !ok {
+		node := createCacheNode(key, value, cost, expire)
 		this.cache[key] = node
 		this.addToHead(node)
 	} else {
-		node := this.cache[key]
+		old := node.value
 		node.value = value
+		node.expire = expire
+		this.cost += cost - node.cost
+		node.cost = cost
 		this.moveToHead(node)
+		this.evict(key, old, EvictReplaced)
+	}
+	return true
+}
+
+func (this *lruCache[K, V]) Remove(key K) bool {
+	node, ok := this.cache[key]
+	if !ok {
+		return false
+	}
+
+	this.deleteNode(node, EvictRemoved)
+	return true
+}
+
+// RemoveExpired deletes all the entries expired at now.
+func (this *lruCache[K, V]) RemoveExpired(now time.Time) {
+	for node := this.tail.prev; node != this.head; {
+		prev := node.prev
+		if node.expired(now) {
+			this.deleteNode(node, EvictExpired)
+		}
+		node = prev
 	}
 }
+
+func (this *lruCache[K, V]) Len() int {
+	return this.size
+}
+
+func (this *lruCache[K, V]) Cost() int64 {
+	return this.cost
+}
+
+// Keys returns the unexpired keys from the most to the least recently used.
+func (this *lruCache[K, V]) Keys() []K {
+	now := time.Now()
+	keys := make([]K, 0, this.size)
+	for node := this.head.next; node != this.tail; node = node.next {
+		if !node.expired(now) {
+			keys = append(keys, node.key)
+		}
+	}
+	return keys
+}
+
+func (this *lruCache[K, V]) Range(fn func(node *cacheNode[K, V])) {
+	for node := this.tail.prev; node != this.head; node = node.prev {
+		fn(node)
+	}
+}
+
+func (this *lruCache[K, V]) Purge() {
+	for node := this.tail.prev; node != this.head; node = node.prev {
+		this.evict(node.key, node.value, EvictRemoved)
+	}
+
+	this.cache = make(map[K]*cacheNode[K, V])
+	this.head.next = this.tail
+	this.tail.prev = this.head
+	this.size = 0
+	this.cost = 0
+}
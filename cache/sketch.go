@@ -0,0 +1,96 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 15:32:18
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 15:32:18
+ * @Description:
+ */
+package cache
+
+const (
+	sketchDepth        = 4
+	maxSketchCount     = 15
+	minSketchWidth     = 64
+	defaultSketchWidth = 1 << 14
+	sampleFactor       = 10
+)
+
+var sketchSeeds = [sketchDepth]uint64{
+	0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325,
+}
+
+// cmSketch is a count-min sketch estimating the access frequency of the keys,
+// the counters saturate at 15 and are halved after every sample period,
+// so that old popularity fades away.
+type cmSketch struct {
+	rows      [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	period    int
+}
+
+func newCMSketch(width int) *cmSketch {
+	if width < minSketchWidth {
+		width = minSketchWidth
+	}
+	width = nextPowerOfTwo(width)
+
+	s := &cmSketch{
+		mask:   uint64(width - 1),
+		period: width * sampleFactor,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *cmSketch) increment(h uint64) {
+	added := false
+	for i := range s.rows {
+		idx := s.index(h, i)
+		if s.rows[i][idx] < maxSketchCount {
+			s.rows[i][idx]++
+			added = true
+		}
+	}
+
+	if added {
+		s.additions++
+		if s.additions >= s.period {
+			s.reset()
+		}
+	}
+}
+
+func (s *cmSketch) estimate(h uint64) uint8 {
+	min := uint8(maxSketchCount)
+	for i := range s.rows {
+		if v := s.rows[i][s.index(h, i)]; v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+func (s *cmSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+func (s *cmSketch) clear() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] = 0
+		}
+	}
+	s.additions = 0
+}
+
+func (s *cmSketch) index(h uint64, row int) uint64 {
+	return mix64(h^sketchSeeds[row]) & s.mask
+}
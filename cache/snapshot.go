@@ -0,0 +1,156 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 20:45:10
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 20:45:10
+ * @Description:
+ */
+package cache
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/cnzf1/gocore/internal/snapshot"
+)
+
+type (
+	// Codec encodes the keys and values of the snapshots.
+	Codec[T any] interface {
+		Marshal(v T) ([]byte, error)
+		Unmarshal(data []byte) (T, error)
+	}
+
+	// JSONCodec is a Codec using encoding/json, it's used by default.
+	JSONCodec[T any] struct{}
+
+	// MarshalerCodec is a Codec using a codec.Marshaler, such as codec.MsgPack.
+	MarshalerCodec[T any] struct {
+		Marshaler codec.Marshaler
+	}
+
+	snapshotEntry[K comparable, V any] struct {
+		key    K
+		value  V
+		expire time.Time
+		cost   int64
+	}
+)
+
+func (JSONCodec[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Unmarshal(data []byte) (v T, err error) {
+	err = json.Unmarshal(data, &v)
+	return
+}
+
+func (c MarshalerCodec[T]) Marshal(v T) ([]byte, error) {
+	return c.Marshaler.Marshal(v)
+}
+
+func (c MarshalerCodec[T]) Unmarshal(data []byte) (v T, err error) {
+	err = c.Marshaler.Unmarshal(data, &v)
+	return
+}
+
+// WithValueCodec sets the codec of the values in the snapshots,
+// for the values which can not be encoded by encoding/json.
+func WithValueCodec[V any](codec Codec[V]) Option {
+	return func(c *config) {
+		c.codec = codec
+	}
+}
+
+// Snapshot writes the unexpired entries to w, with their remaining ttl and cost.
+// The keys are encoded as JSON, and the values by the codec set by WithValueCodec.
+func (c *LRU[K, V]) Snapshot(w io.Writer) error {
+	sw, err := snapshot.NewWriter(w)
+	if err != nil {
+		return err
+	}
+
+	keyCodec := JSONCodec[K]{}
+	now := time.Now()
+	for _, s := range c.shards {
+		// copy the entries, not to hold the lock while encoding them.
+		var entries []snapshotEntry[K, V]
+		s.lock.Lock()
+		s.Range(func(node *cacheNode[K, V]) {
+			if !node.expired(now) {
+				entries = append(entries, snapshotEntry[K, V]{
+					key:    node.key,
+					value:  node.value,
+					expire: node.expire,
+					cost:   node.cost,
+				})
+			}
+		})
+		s.lock.Unlock()
+
+		for _, e := range entries {
+			key, err := keyCodec.Marshal(e.key)
+			if err != nil {
+				return err
+			}
+			value, err := c.codec.Marshal(e.value)
+			if err != nil {
+				return err
+			}
+
+			var expire int64
+			if !e.expire.IsZero() {
+				expire = e.expire.UnixNano()
+			}
+			if err := sw.Write(snapshot.Entry{
+				Key:    key,
+				Value:  value,
+				Expire: expire,
+				Cost:   e.cost,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return sw.Close()
+}
+
+// Restore adds the entries of a snapshot written by Snapshot, in their order of use.
+// The entries expired since the snapshot are skipped.
+func (c *LRU[K, V]) Restore(r io.Reader) error {
+	sr, err := snapshot.NewReader(r)
+	if err != nil {
+		return err
+	}
+
+	keyCodec := JSONCodec[K]{}
+	for {
+		e, err := sr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var ttl time.Duration
+		if e.Expire != 0 {
+			if ttl = time.Until(time.Unix(0, e.Expire)); ttl <= 0 {
+				continue
+			}
+		}
+
+		key, err := keyCodec.Unmarshal(e.Key)
+		if err != nil {
+			return err
+		}
+		value, err := c.codec.Unmarshal(e.Value)
+		if err != nil {
+			return err
+		}
+		c.PutWithCost(key, value, e.Cost, ttl)
+	}
+}
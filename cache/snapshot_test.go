@@ -0,0 +1,108 @@
+package cache_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cnzf1/gocore/cache"
+	"github.com/cnzf1/gocore/codec"
+	"github.com/cnzf1/gocore/internal/snapshot"
+	"github.com/stretchr/testify/assert"
+)
+
+type upperCodec struct{}
+
+func (upperCodec) Marshal(v []byte) ([]byte, error) {
+	return bytes.ToUpper(v), nil
+}
+
+func (upperCodec) Unmarshal(data []byte) ([]byte, error) {
+	return bytes.ToLower(data), nil
+}
+
+func TestLRUSnapshot(t *testing.T) {
+	c := cache.NewLRU[string, int](3, cache.WithShards(1))
+	c.Put("a", 1)
+	c.PutWithTTL("b", 2, time.Hour)
+	c.PutWithTTL("c", 3, 10*time.Millisecond)
+	c.Put("d", 4)
+	c.Get("b")
+	time.Sleep(20 * time.Millisecond)
+
+	var buf bytes.Buffer
+	assert.Nil(t, c.Snapshot(&buf))
+
+	restored := cache.NewLRU[string, int](3, cache.WithShards(1))
+	assert.Nil(t, restored.Restore(&buf))
+	assert.Equal(t, []string{"b", "d"}, restored.Keys())
+
+	v, ok := restored.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+}
+
+func TestLRUSnapshotCodec(t *testing.T) {
+	c := cache.NewLRU[int, []byte](100, cache.WithValueCodec[[]byte](upperCodec{}))
+	for i := 0; i < 100; i++ {
+		c.Put(i, []byte("v"+strconv.Itoa(i)))
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, c.Snapshot(&buf))
+	assert.True(t, bytes.Contains(buf.Bytes(), []byte("V42")))
+
+	restored := cache.NewLRU[int, []byte](100, cache.WithValueCodec[[]byte](upperCodec{}))
+	assert.Nil(t, restored.Restore(&buf))
+	assert.Equal(t, 100, restored.Len())
+	v, ok := restored.Get(42)
+	assert.True(t, ok)
+	assert.Equal(t, "v42", string(v))
+}
+
+func TestLRURestoreBadFormat(t *testing.T) {
+	c := cache.NewLRU[string, int](10)
+	assert.Equal(t, snapshot.ErrBadFormat, c.Restore(bytes.NewBufferString("foo")))
+
+	data := []byte("GCSNAP")
+	data = append(data, snapshot.Version+1)
+	assert.Equal(t, snapshot.ErrVersion, c.Restore(bytes.NewBuffer(data)))
+
+	// a key claiming 1GiB in a few bytes fails without allocating it.
+	var size [binary.MaxVarintLen64]byte
+	data = []byte("GCSNAP")
+	data = append(data, snapshot.Version, 1)
+	data = append(data, size[:binary.PutUvarint(size[:], 1<<30)]...)
+	data = append(data, "foo"...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	assert.Equal(t, snapshot.ErrBadFormat, c.Restore(bytes.NewBuffer(data)))
+	runtime.ReadMemStats(&after)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+}
+
+func TestLRUSnapshotMarshaler(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+
+	m, err := codec.Compress(codec.Gob, codec.CompressGzip)
+	assert.Nil(t, err)
+	valueCodec := cache.MarshalerCodec[point]{Marshaler: m}
+
+	c := cache.NewLRU[string, point](10, cache.WithValueCodec[point](valueCodec))
+	c.Put("a", point{1, 2})
+
+	var buf bytes.Buffer
+	assert.Nil(t, c.Snapshot(&buf))
+
+	restored := cache.NewLRU[string, point](10, cache.WithValueCodec[point](valueCodec))
+	assert.Nil(t, restored.Restore(&buf))
+	v, ok := restored.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, point{1, 2}, v)
+}
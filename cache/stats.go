@@ -0,0 +1,121 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 22:10:26
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 22:10:26
+ * @Description:
+ */
+package cache
+
+import (
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+type (
+	// Stats is a snapshot of the counters of a cache.
+	Stats struct {
+		Hits       uint64
+		Misses     uint64
+		Loads      uint64
+		LoadErrors uint64
+		// Evictions is the number of entries which left the cache, by reason.
+		Evictions map[EvictReason]uint64
+		Size      int
+		Cost      int64
+	}
+
+	// StatsHook is notified of the cache events as they happen,
+	// it's meant to feed a metrics registry. The methods must not block.
+	StatsHook interface {
+		OnHit()
+		OnMiss()
+		// OnLoad is called after a LoadingCache loaded a value, err is the
+		// error returned by the loader.
+		OnLoad(elapsed time.Duration, err error)
+		OnEvict(reason EvictReason)
+	}
+
+	statsCounter struct {
+		hits       atomic.Uint64
+		misses     atomic.Uint64
+		loads      atomic.Uint64
+		loadErrors atomic.Uint64
+	}
+)
+
+// WithStatsHook sets the hook notified of the cache events.
+func WithStatsHook(hook StatsHook) Option {
+	return func(c *config) {
+		c.hook = hook
+	}
+}
+
+// Requests returns the number of lookups.
+func (s Stats) Requests() uint64 {
+	return s.Hits + s.Misses
+}
+
+// HitRatio returns the ratio of the lookups which hit the cache,
+// or 0 if there was no lookup.
+func (s Stats) HitRatio() float64 {
+	requests := s.Requests()
+	if requests == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(requests)
+}
+
+// Stats returns the counters of the cache since it was created.
+func (c *LRU[K, V]) Stats() Stats {
+	stats := Stats{
+		Hits:       c.stats.hits.Load(),
+		Misses:     c.stats.misses.Load(),
+		Loads:      c.stats.loads.Load(),
+		LoadErrors: c.stats.loadErrors.Load(),
+		Evictions:  make(map[EvictReason]uint64, evictReasons),
+	}
+
+	var counts [evictReasons]uint64
+	for _, s := range c.shards {
+		s.lock.Lock()
+		stats.Size += s.Len()
+		stats.Cost += s.Cost()
+		shardCounts := s.evictedCounts()
+		s.lock.Unlock()
+
+		for i, n := range shardCounts {
+			counts[i] += n
+		}
+	}
+	for i, n := range counts {
+		stats.Evictions[EvictReason(i)] = n
+	}
+
+	return stats
+}
+
+func (c *LRU[K, V]) recordAccess(hit bool) {
+	if hit {
+		c.stats.hits.Inc()
+		if c.hook != nil {
+			c.hook.OnHit()
+		}
+	} else {
+		c.stats.misses.Inc()
+		if c.hook != nil {
+			c.hook.OnMiss()
+		}
+	}
+}
+
+func (c *LRU[K, V]) recordLoad(elapsed time.Duration, err error) {
+	c.stats.loads.Inc()
+	if err != nil {
+		c.stats.loadErrors.Inc()
+	}
+	if c.hook != nil {
+		c.hook.OnLoad(elapsed, err)
+	}
+}
@@ -0,0 +1,98 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cnzf1/gocore/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type testHook struct {
+	lock      sync.Mutex
+	hits      int
+	misses    int
+	loads     int
+	evictions map[cache.EvictReason]int
+}
+
+func (h *testHook) OnHit() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.hits++
+}
+
+func (h *testHook) OnMiss() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.misses++
+}
+
+func (h *testHook) OnLoad(elapsed time.Duration, err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.loads++
+}
+
+func (h *testHook) OnEvict(reason cache.EvictReason) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.evictions[reason]++
+}
+
+func TestLRUStats(t *testing.T) {
+	for _, policy := range []cache.Policy{cache.PolicyLRU, cache.PolicyTinyLFU} {
+		hook := &testHook{evictions: make(map[cache.EvictReason]int)}
+		c := cache.NewLRU[string, int](2, cache.WithShards(1), cache.WithPolicy(policy),
+			cache.WithStatsHook(hook))
+		c.Put("a", 1)
+		c.Put("a", 2)
+		c.Get("a")
+		c.Get("b")
+		c.Peek("a")
+		c.Remove("a")
+
+		stats := c.Stats()
+		assert.Equal(t, uint64(1), stats.Hits)
+		assert.Equal(t, uint64(1), stats.Misses)
+		assert.Equal(t, 0.5, stats.HitRatio())
+		assert.Equal(t, uint64(1), stats.Evictions[cache.EvictReplaced])
+		assert.Equal(t, uint64(1), stats.Evictions[cache.EvictRemoved])
+		assert.Equal(t, uint64(0), stats.Evictions[cache.EvictCapacity])
+		assert.Equal(t, 0, stats.Size)
+
+		assert.Equal(t, 1, hook.hits)
+		assert.Equal(t, 1, hook.misses)
+		assert.Equal(t, 1, hook.evictions[cache.EvictReplaced])
+		assert.Equal(t, 1, hook.evictions[cache.EvictRemoved])
+	}
+
+	assert.Equal(t, float64(0), cache.Stats{}.HitRatio())
+}
+
+func TestLoadingCacheStats(t *testing.T) {
+	hook := &testHook{evictions: make(map[cache.EvictReason]int)}
+	c := cache.NewLoadingCache[string, int](10, cache.WithStatsHook(hook))
+	errLoad := errors.New("load")
+	loader := func(ctx context.Context, key string) (int, error) {
+		if key == "bad" {
+			return 0, errLoad
+		}
+		return len(key), nil
+	}
+
+	c.GetOrLoad(context.Background(), "abc", loader)
+	c.GetOrLoad(context.Background(), "abc", loader)
+	c.GetOrLoad(context.Background(), "bad", loader)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, uint64(2), stats.Loads)
+	assert.Equal(t, uint64(1), stats.LoadErrors)
+	assert.Equal(t, 1, stats.Size)
+	assert.Equal(t, 2, hook.loads)
+}
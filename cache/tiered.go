@@ -0,0 +1,439 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 19:02:36
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 19:02:36
+ * @Description:
+ */
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cnzf1/gocore/errorx"
+	"github.com/cnzf1/gocore/lang"
+	"github.com/cnzf1/gocore/thread"
+	"go.uber.org/atomic"
+)
+
+const defaultFlushInterval = time.Second
+
+type (
+	// Tiered is a two-tier cache, an in-process LRU in front of a shared Backend.
+	// Reads go through the LRU to the backend, writes are applied to the backend
+	// at once, or in batches in write-back mode. When an invalidator is set, the
+	// keys changed by other processes are removed from the LRU.
+	Tiered struct {
+		l1       *LRU[string, []byte]
+		l2       Backend
+		inv      Invalidator
+		source   string
+		ttl      time.Duration
+		cancel   func()
+		onError  func(error)
+		dirty    map[string]dirtyEntry
+		flushing map[string]dirtyEntry
+		// the keys deleted during the flush, never written nor requeued by it.
+		deleted   map[string]lang.PlaceholderType
+		lock      sync.Mutex
+		flushLock sync.Mutex
+		// gen is bumped under genLock whenever the LRU is written or invalidated,
+		// a read from the backend only fills the LRU if gen didn't change meanwhile.
+		gen       atomic.Uint64
+		genLock   sync.Mutex
+		writeBack bool
+		stop      chan lang.PlaceholderType
+		done      chan lang.PlaceholderType
+		once      sync.Once
+	}
+
+	dirtyEntry struct {
+		value []byte
+		ttl   time.Duration
+	}
+
+	flushEntry struct {
+		key string
+		dirtyEntry
+	}
+
+	tieredConfig struct {
+		inv       Invalidator
+		source    string
+		ttl       time.Duration
+		writeBack bool
+		interval  time.Duration
+		onError   func(error)
+	}
+
+	// TieredOption customizes a Tiered.
+	TieredOption func(*tieredConfig)
+)
+
+// WithInvalidator sets the invalidator used to keep the LRU of several processes in sync.
+func WithInvalidator(inv Invalidator) TieredOption {
+	return func(c *tieredConfig) {
+		c.inv = inv
+	}
+}
+
+// WithSource sets the name the Tiered publishes its invalidations with,
+// a random one is used by default.
+func WithSource(source string) TieredOption {
+	return func(c *tieredConfig) {
+		c.source = source
+	}
+}
+
+// WithBackendTTL sets the ttl of the values written to the backend.
+func WithBackendTTL(ttl time.Duration) TieredOption {
+	return func(c *tieredConfig) {
+		c.ttl = ttl
+	}
+}
+
+// WithWriteBack makes Set write the backend in the background every interval,
+// instead of at once. Call Flush or Close to write the pending values.
+func WithWriteBack(interval time.Duration) TieredOption {
+	return func(c *tieredConfig) {
+		c.writeBack = true
+		c.interval = interval
+	}
+}
+
+// WithErrorHandler sets the function called with the errors of the background writes.
+func WithErrorHandler(fn func(error)) TieredOption {
+	return func(c *tieredConfig) {
+		c.onError = fn
+	}
+}
+
+// NewTiered returns a Tiered using l1 in front of l2, the ttl of l1 bounds how
+// stale its values can be when no invalidator is set.
+func NewTiered(l1 *LRU[string, []byte], l2 Backend, opts ...TieredOption) *Tiered {
+	cfg := &tieredConfig{
+		interval: defaultFlushInterval,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.source == "" {
+		cfg.source = randomSource()
+	}
+	if cfg.interval <= 0 {
+		cfg.interval = defaultFlushInterval
+	}
+
+	t := &Tiered{
+		l1:        l1,
+		l2:        l2,
+		inv:       cfg.inv,
+		source:    cfg.source,
+		ttl:       cfg.ttl,
+		onError:   cfg.onError,
+		dirty:     make(map[string]dirtyEntry),
+		writeBack: cfg.writeBack,
+		stop:      make(chan lang.PlaceholderType),
+		done:      make(chan lang.PlaceholderType),
+	}
+
+	if t.inv != nil {
+		t.cancel = t.inv.Subscribe(t.invalidate)
+	}
+
+	if t.writeBack {
+		t.flushLoop(cfg.interval)
+	} else {
+		close(t.done)
+	}
+	return t
+}
+
+// Get returns a copy of the value of key, or ErrNotFound if key does not exist.
+func (t *Tiered) Get(ctx context.Context, key string) ([]byte, error) {
+	gen := t.gen.Load()
+	if v, ok := t.l1.Get(key); ok {
+		return copyBytes(v), nil
+	}
+	if v, ok := t.pending(key); ok {
+		return copyBytes(v), nil
+	}
+
+	v, err := t.l2.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	t.fill(gen, map[string][]byte{key: v})
+	return copyBytes(v), nil
+}
+
+// MGet returns the values of the existing keys, the missing keys are absent from the result.
+func (t *Tiered) MGet(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	gen := t.gen.Load()
+	values := make(map[string][]byte, len(keys))
+	var missing []string
+	for _, key := range keys {
+		if v, ok := t.l1.Get(key); ok {
+			values[key] = copyBytes(v)
+		} else if v, ok := t.pending(key); ok {
+			values[key] = copyBytes(v)
+		} else {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	remote, err := t.l2.MGet(ctx, missing...)
+	if err != nil {
+		return nil, err
+	}
+
+	t.fill(gen, remote)
+	for key, v := range remote {
+		values[key] = copyBytes(v)
+	}
+	return values, nil
+}
+
+// Set sets the value of key in both tiers, in write-back mode the backend is
+// written later.
+func (t *Tiered) Set(ctx context.Context, key string, value []byte) error {
+	return t.SetWithTTL(ctx, key, value, t.ttl)
+}
+
+// SetWithTTL is like Set, but the value expires from the backend after ttl.
+func (t *Tiered) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	value = copyBytes(value)
+	t.update(func() {
+		t.l1.Put(key, value)
+	})
+
+	if t.writeBack {
+		t.lock.Lock()
+		t.dirty[key] = dirtyEntry{value: value, ttl: ttl}
+		t.lock.Unlock()
+		return nil
+	}
+
+	if err := t.l2.Set(ctx, key, value, ttl); err != nil {
+		t.remove(key)
+		return err
+	}
+
+	return t.publish(ctx, key)
+}
+
+// Delete deletes the keys from both tiers.
+func (t *Tiered) Delete(ctx context.Context, keys ...string) error {
+	t.lock.Lock()
+	for _, key := range keys {
+		delete(t.dirty, key)
+		delete(t.flushing, key)
+		if t.deleted != nil {
+			t.deleted[key] = lang.Placeholder
+		}
+	}
+	t.lock.Unlock()
+
+	t.remove(keys...)
+
+	if err := t.l2.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	return t.publish(ctx, keys...)
+}
+
+// Flush writes the pending values to the backend.
+func (t *Tiered) Flush(ctx context.Context) error {
+	t.flushLock.Lock()
+	defer t.flushLock.Unlock()
+
+	// the values being written stay visible to pending until they are written.
+	t.lock.Lock()
+	entries := make([]flushEntry, 0, len(t.dirty))
+	for key, e := range t.dirty {
+		entries = append(entries, flushEntry{key: key, dirtyEntry: e})
+	}
+	t.flushing = t.dirty
+	t.dirty = make(map[string]dirtyEntry)
+	t.deleted = make(map[string]lang.PlaceholderType)
+	t.lock.Unlock()
+
+	defer func() {
+		t.lock.Lock()
+		t.flushing = nil
+		t.deleted = nil
+		t.lock.Unlock()
+	}()
+
+	var be errorx.BatchError
+	var written []string
+	for _, e := range entries {
+		if t.isDeleted(e.key) {
+			continue
+		}
+
+		if err := t.l2.Set(ctx, e.key, e.value, e.ttl); err != nil {
+			be.Add(err)
+			t.requeue(e.key, e.dirtyEntry)
+			continue
+		}
+
+		// the delete may have reached the backend before the write.
+		if t.isDeleted(e.key) {
+			be.Add(t.l2.Delete(ctx, e.key))
+			continue
+		}
+		written = append(written, e.key)
+	}
+
+	if len(written) > 0 {
+		be.Add(t.publish(ctx, written...))
+	}
+	return be.Err()
+}
+
+// Close writes the pending values and stops the background goroutines.
+func (t *Tiered) Close() error {
+	var err error
+	t.once.Do(func() {
+		if t.cancel != nil {
+			t.cancel()
+		}
+		close(t.stop)
+		<-t.done
+		if t.writeBack {
+			err = t.Flush(context.Background())
+		}
+	})
+	return err
+}
+
+func (t *Tiered) flushLoop(interval time.Duration) {
+	thread.GoSafe(func() {
+		defer close(t.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := t.Flush(context.Background()); err != nil && t.onError != nil {
+					t.onError(err)
+				}
+			case <-t.stop:
+				return
+			}
+		}
+	})
+}
+
+// requeue puts back a value failed to be written, unless it was changed or deleted since.
+func (t *Tiered) requeue(key string, e dirtyEntry) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.deleted[key]; ok {
+		return
+	}
+	if _, ok := t.dirty[key]; !ok {
+		t.dirty[key] = e
+	}
+}
+
+// isDeleted reports whether key was deleted during the flush.
+func (t *Tiered) isDeleted(key string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	_, ok := t.deleted[key]
+	return ok
+}
+
+func (t *Tiered) pending(key string) ([]byte, bool) {
+	if !t.writeBack {
+		return nil, false
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if e, ok := t.dirty[key]; ok {
+		return e.value, true
+	}
+	e, ok := t.flushing[key]
+	return e.value, ok
+}
+
+func (t *Tiered) publish(ctx context.Context, keys ...string) error {
+	if t.inv == nil {
+		return nil
+	}
+
+	return t.inv.Publish(ctx, Invalidation{
+		Source: t.source,
+		Keys:   keys,
+	})
+}
+
+func (t *Tiered) invalidate(msg Invalidation) {
+	if msg.Source == t.source {
+		return
+	}
+
+	t.remove(msg.Keys...)
+}
+
+// update applies fn to the LRU, the reads from the backend started before are
+// not filled in the LRU, their values may be stale.
+func (t *Tiered) update(fn func()) {
+	t.genLock.Lock()
+	defer t.genLock.Unlock()
+
+	t.gen.Inc()
+	fn()
+}
+
+func (t *Tiered) remove(keys ...string) {
+	t.update(func() {
+		for _, key := range keys {
+			t.l1.Remove(key)
+		}
+	})
+}
+
+// fill puts the values read from the backend in the LRU, unless it was updated
+// since gen was loaded.
+func (t *Tiered) fill(gen uint64, values map[string][]byte) {
+	t.genLock.Lock()
+	defer t.genLock.Unlock()
+
+	if t.gen.Load() != gen {
+		return
+	}
+	for key, v := range values {
+		t.l1.Put(key, v)
+	}
+}
+
+func randomSource() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// no entropy available, the host, pid and time still tell the processes apart.
+		host, _ := os.Hostname()
+		return fmt.Sprintf("%s-%d-%x", host, os.Getpid(), time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
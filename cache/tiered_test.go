@@ -0,0 +1,228 @@
+package cache_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cnzf1/gocore/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBackend(t *testing.T) {
+	ctx := context.Background()
+	b := cache.NewMemoryBackend()
+
+	assert.Nil(t, b.Set(ctx, "a", []byte("1"), 0))
+	assert.Nil(t, b.Set(ctx, "b", []byte("2"), 20*time.Millisecond))
+
+	v, err := b.Get(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", string(v))
+
+	values, err := b.MGet(ctx, "a", "b", "c")
+	assert.Nil(t, err)
+	assert.Len(t, values, 2)
+
+	time.Sleep(50 * time.Millisecond)
+	_, err = b.Get(ctx, "b")
+	assert.Equal(t, cache.ErrNotFound, err)
+
+	assert.Nil(t, b.Delete(ctx, "a", "c"))
+	_, err = b.Get(ctx, "a")
+	assert.Equal(t, cache.ErrNotFound, err)
+}
+
+func TestTieredReadThrough(t *testing.T) {
+	ctx := context.Background()
+	l1 := cache.NewLRU[string, []byte](10)
+	l2 := cache.NewMemoryBackend()
+	tc := cache.NewTiered(l1, l2)
+	defer tc.Close()
+
+	l2.Set(ctx, "a", []byte("1"), 0)
+	l2.Set(ctx, "b", []byte("2"), 0)
+
+	v, err := tc.Get(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", string(v))
+	_, ok := l1.Peek("a")
+	assert.True(t, ok)
+
+	values, err := tc.MGet(ctx, "a", "b", "c")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]byte{"a": []byte("1"), "b": []byte("2")}, values)
+
+	_, err = tc.Get(ctx, "c")
+	assert.Equal(t, cache.ErrNotFound, err)
+}
+
+func TestTieredInvalidation(t *testing.T) {
+	ctx := context.Background()
+	l2 := cache.NewMemoryBackend()
+	inv := cache.NewLocalInvalidator()
+	tc1 := cache.NewTiered(cache.NewLRU[string, []byte](10), l2, cache.WithInvalidator(inv))
+	tc2 := cache.NewTiered(cache.NewLRU[string, []byte](10), l2, cache.WithInvalidator(inv))
+	defer tc1.Close()
+	defer tc2.Close()
+
+	assert.Nil(t, tc1.Set(ctx, "a", []byte("1")))
+	v, err := tc2.Get(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", string(v))
+
+	assert.Nil(t, tc1.Set(ctx, "a", []byte("2")))
+	v, err = tc2.Get(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", string(v))
+
+	assert.Nil(t, tc2.Delete(ctx, "a"))
+	_, err = tc1.Get(ctx, "a")
+	assert.Equal(t, cache.ErrNotFound, err)
+}
+
+func TestTieredWriteBack(t *testing.T) {
+	ctx := context.Background()
+	l2 := cache.NewMemoryBackend()
+	tc := cache.NewTiered(cache.NewLRU[string, []byte](1), l2, cache.WithWriteBack(time.Hour))
+
+	assert.Nil(t, tc.Set(ctx, "a", []byte("1")))
+	assert.Nil(t, tc.Set(ctx, "b", []byte("2")))
+	_, err := l2.Get(ctx, "a")
+	assert.Equal(t, cache.ErrNotFound, err)
+
+	// a was pushed out of l1, but it's still pending.
+	v, err := tc.Get(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", string(v))
+
+	assert.Nil(t, tc.Close())
+	v, err = l2.Get(ctx, "b")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", string(v))
+}
+
+// slowBackend makes the writes slow enough for the deletes to interleave with a flush.
+type slowBackend struct {
+	*cache.MemoryBackend
+}
+
+func (b slowBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	time.Sleep(100 * time.Microsecond)
+	return b.MemoryBackend.Set(ctx, key, value, ttl)
+}
+
+func TestTieredFlushDelete(t *testing.T) {
+	ctx := context.Background()
+	l2 := slowBackend{cache.NewMemoryBackend()}
+	tc := cache.NewTiered(cache.NewLRU[string, []byte](100), l2, cache.WithWriteBack(time.Hour))
+	defer tc.Close()
+
+	for round := 0; round < 20; round++ {
+		keys := make([]string, 50)
+		for i := range keys {
+			keys[i] = strconv.Itoa(round*100 + i)
+			assert.Nil(t, tc.Set(ctx, keys[i], []byte("v")))
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, tc.Flush(ctx))
+		}()
+		go func() {
+			defer wg.Done()
+			for _, key := range keys {
+				assert.Nil(t, tc.Delete(ctx, key))
+			}
+		}()
+		wg.Wait()
+
+		// the deleted keys never come back, from either tier.
+		assert.Nil(t, tc.Flush(ctx))
+		for _, key := range keys {
+			_, err := tc.Get(ctx, key)
+			assert.Equal(t, cache.ErrNotFound, err, key)
+		}
+	}
+}
+
+func TestTieredCopy(t *testing.T) {
+	ctx := context.Background()
+	l2 := cache.NewMemoryBackend()
+	tc := cache.NewTiered(cache.NewLRU[string, []byte](10), l2)
+	defer tc.Close()
+
+	value := []byte("1")
+	assert.Nil(t, tc.Set(ctx, "a", value))
+	value[0] = 'x'
+
+	v, err := tc.Get(ctx, "a")
+	assert.Nil(t, err)
+	v[0] = 'y'
+
+	values, err := tc.MGet(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", string(values["a"]))
+	values["a"][0] = 'z'
+
+	v, err = tc.Get(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", string(v))
+}
+
+// gatedBackend blocks the first Get after it read the value, until release is closed.
+type gatedBackend struct {
+	*cache.MemoryBackend
+	once    sync.Once
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (b *gatedBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := b.MemoryBackend.Get(ctx, key)
+	b.once.Do(func() {
+		close(b.read)
+		<-b.release
+	})
+	return v, err
+}
+
+func TestTieredStaleFill(t *testing.T) {
+	ctx := context.Background()
+	l2 := &gatedBackend{
+		MemoryBackend: cache.NewMemoryBackend(),
+		read:          make(chan struct{}),
+		release:       make(chan struct{}),
+	}
+	inv := cache.NewLocalInvalidator()
+	l1 := cache.NewLRU[string, []byte](10)
+	tc := cache.NewTiered(l1, l2, cache.WithInvalidator(inv))
+	other := cache.NewTiered(cache.NewLRU[string, []byte](10), l2.MemoryBackend, cache.WithInvalidator(inv))
+	defer tc.Close()
+	defer other.Close()
+
+	assert.Nil(t, l2.Set(ctx, "a", []byte("1"), 0))
+
+	done := make(chan []byte)
+	go func() {
+		v, err := tc.Get(ctx, "a")
+		assert.Nil(t, err)
+		done <- v
+	}()
+
+	// a is changed while tc reads its previous value.
+	<-l2.read
+	assert.Nil(t, other.Set(ctx, "a", []byte("2")))
+	close(l2.release)
+	assert.Equal(t, "1", string(<-done))
+
+	_, ok := l1.Peek("a")
+	assert.False(t, ok)
+	v, err := tc.Get(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", string(v))
+}
@@ -0,0 +1,382 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 15:32:18
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 22:10:26
+ * @Description:
+ */
+package cache
+
+import "time"
+
+const (
+	segWindow uint8 = iota
+	segProbation
+	segProtected
+
+	windowPercent    = 1
+	protectedPercent = 80
+	// sketchPerEntry is the number of counters per entry in each row of the sketch.
+	sketchPerEntry = 4
+)
+
+// tinyLFUCache is a W-TinyLFU cache, it's not concurrent safe.
+//
+// New entries go to a small LRU window, the entries leaving the window become
+// candidates for the main space, which is a segmented LRU made of a probation
+// and a protected segment. When the cache is full, a candidate is compared with
+// the least recently used entry of the probation segment, and the one used less
+// often according to the frequency sketch is evicted, so one-off scans can not
+// flush the popular entries.
+type tinyLFUCache[K comparable, V any] struct {
+	capacity      int
+	maxCost       int64
+	windowCap     int
+	windowCost    int64
+	protectedCap  int
+	protectedCost int64
+	size          int
+	cost          int64
+	cache         map[K]*cacheNode[K, V]
+	window        nodeList[K, V]
+	probation     nodeList[K, V]
+	protected     nodeList[K, V]
+	sketch        *cmSketch
+	hash          func(K) uint64
+	evictions[K, V]
+}
+
+// nodeList is a doubly linked list of cacheNode with sentinels.
+type nodeList[K comparable, V any] struct {
+	head, tail *cacheNode[K, V]
+	len        int
+	cost       int64
+}
+
+func newTinyLFUCache[K comparable, V any](capacity int, maxCost int64, hash func(K) uint64,
+	notify bool) *tinyLFUCache[K, V] {
+	c := &tinyLFUCache[K, V]{
+		capacity: capacity,
+		maxCost:  maxCost,
+		cache:    make(map[K]*cacheNode[K, V]),
+		hash:     hash,
+	}
+	c.notify = notify
+
+	width := defaultSketchWidth
+	if capacity > 0 {
+		c.windowCap = maxInt(1, capacity*windowPercent/100)
+		c.protectedCap = (capacity - c.windowCap) * protectedPercent / 100
+		width = capacity * sketchPerEntry
+	}
+	if maxCost > 0 {
+		c.windowCost = maxInt64(1, maxCost*windowPercent/100)
+		c.protectedCost = (maxCost - c.windowCost) * protectedPercent / 100
+	}
+	c.sketch = newCMSketch(width)
+
+	c.window.init()
+	c.probation.init()
+	c.protected.init()
+	return c
+}
+
+func (this *tinyLFUCache[K, V]) Get(key K) (value V, ok bool) {
+	this.sketch.increment(this.hash(key))
+
+	node, ok := this.cache[key]
+	if !ok {
+		return
+	}
+
+	if node.expired(time.Now()) {
+		this.deleteNode(node, EvictExpired)
+		return value, false
+	}
+
+	this.onHit(node)
+	return node.value, true
+}
+
+// Peek returns the value of key without updating its recency and frequency.
+func (this *tinyLFUCache[K, V]) Peek(key K) (value V, ok bool) {
+	node, ok := this.cache[key]
+	if !ok {
+		return
+	}
+
+	if node.expired(time.Now()) {
+		this.deleteNode(node, EvictExpired)
+		return value, false
+	}
+
+	return node.value, true
+}
+
+// Put adds or updates the value of key, a zero expire means never expire.
+// It returns false if cost exceeds maxCost, and the stale value of key is removed.
+func (this *tinyLFUCache[K, V]) Put(key K, value V, cost int64, expire time.Time) bool {
+	if this.maxCost > 0 && cost > this.maxCost {
+		if node, ok := this.cache[key]; ok {
+			this.deleteNode(node, EvictReplaced)
+		}
+		return false
+	}
+
+	this.sketch.increment(this.hash(key))
+
+	if node, ok := this.cache[key]; ok {
+		old := node.value
+		node.value = value
+		node.expire = expire
+		this.segment(node).cost += cost - node.cost
+		this.cost += cost - node.cost
+		node.cost = cost
+		this.onHit(node)
+		this.evict(key, old, EvictReplaced)
+	} else {
+		node := createCacheNode(key, value, cost, expire)
+		node.segment = segWindow
+		this.cache[key] = node
+		this.window.pushFront(node)
+		this.size++
+		this.cost += cost
+	}
+
+	this.shrink()
+	return true
+}
+
+func (this *tinyLFUCache[K, V]) Remove(key K) bool {
+	node, ok := this.cache[key]
+	if !ok {
+		return false
+	}
+
+	this.deleteNode(node, EvictRemoved)
+	return true
+}
+
+// RemoveExpired deletes all the entries expired at now.
+func (this *tinyLFUCache[K, V]) RemoveExpired(now time.Time) {
+	for _, l := range []*nodeList[K, V]{&this.window, &this.probation, &this.protected} {
+		for node := l.tail.prev; node != l.head; {
+			prev := node.prev
+			if node.expired(now) {
+				this.deleteNode(node, EvictExpired)
+			}
+			node = prev
+		}
+	}
+}
+
+func (this *tinyLFUCache[K, V]) Len() int {
+	return this.size
+}
+
+func (this *tinyLFUCache[K, V]) Cost() int64 {
+	return this.cost
+}
+
+// Keys returns the unexpired keys of the window, protected and probation segments,
+// each one from the most to the least recently used.
+func (this *tinyLFUCache[K, V]) Keys() []K {
+	now := time.Now()
+	keys := make([]K, 0, this.size)
+	for _, l := range []*nodeList[K, V]{&this.window, &this.protected, &this.probation} {
+		for node := l.head.next; node != l.tail; node = node.next {
+			if !node.expired(now) {
+				keys = append(keys, node.key)
+			}
+		}
+	}
+	return keys
+}
+
+// Range calls fn with the entries of the probation, protected and window segments,
+// each one from the least to the most recently used.
+func (this *tinyLFUCache[K, V]) Range(fn func(node *cacheNode[K, V])) {
+	for _, l := range []*nodeList[K, V]{&this.probation, &this.protected, &this.window} {
+		for node := l.tail.prev; node != l.head; node = node.prev {
+			fn(node)
+		}
+	}
+}
+
+func (this *tinyLFUCache[K, V]) Purge() {
+	for _, l := range []*nodeList[K, V]{&this.probation, &this.protected, &this.window} {
+		for node := l.tail.prev; node != l.head; node = node.prev {
+			this.evict(node.key, node.value, EvictRemoved)
+		}
+	}
+
+	this.cache = make(map[K]*cacheNode[K, V])
+	this.window.init()
+	this.probation.init()
+	this.protected.init()
+	this.sketch.clear()
+	this.size = 0
+	this.cost = 0
+}
+
+func (this *tinyLFUCache[K, V]) deleteNode(node *cacheNode[K, V], reason EvictReason) {
+	delete(this.cache, node.key)
+	this.segment(node).remove(node)
+	this.size--
+	this.cost -= node.cost
+	this.evict(node.key, node.value, reason)
+}
+
+// onHit updates the recency of node, the entries hit in the probation segment
+// are promoted to the protected one.
+func (this *tinyLFUCache[K, V]) onHit(node *cacheNode[K, V]) {
+	switch node.segment {
+	case segWindow:
+		this.window.moveToFront(node)
+	case segProtected:
+		// an update may have raised the cost of node over the protected share.
+		this.protected.moveToFront(node)
+		this.demote()
+	case segProbation:
+		this.probation.remove(node)
+		node.segment = segProtected
+		this.protected.pushFront(node)
+		this.demote()
+	}
+}
+
+// demote moves the least recently used entries of the protected segment to the
+// probation segment, until the protected segment fits its share.
+func (this *tinyLFUCache[K, V]) demote() {
+	for this.protectedOverflow() {
+		demoted := this.protected.back()
+		this.protected.remove(demoted)
+		demoted.segment = segProbation
+		this.probation.pushFront(demoted)
+	}
+}
+
+// shrink moves the entries overflowing the window to the probation segment,
+// and evicts entries until the cache fits its limits.
+func (this *tinyLFUCache[K, V]) shrink() {
+	var candidates []*cacheNode[K, V]
+	for this.window.len > 1 && this.windowOverflow() {
+		node := this.window.back()
+		this.window.remove(node)
+		node.segment = segProbation
+		this.probation.pushFront(node)
+		candidates = append(candidates, node)
+	}
+
+	for this.size > 0 && this.overflow() {
+		victim := this.probation.back()
+		if victim == nil {
+			victim = this.protected.back()
+		}
+		if victim == nil {
+			victim = this.window.back()
+		}
+
+		if len(candidates) == 0 || candidates[0] == victim {
+			if len(candidates) > 0 {
+				candidates = candidates[1:]
+			}
+			this.deleteNode(victim, EvictCapacity)
+			continue
+		}
+
+		candidate := candidates[0]
+		if this.sketch.estimate(this.hash(candidate.key)) > this.sketch.estimate(this.hash(victim.key)) {
+			this.deleteNode(victim, EvictCapacity)
+		} else {
+			candidates = candidates[1:]
+			this.deleteNode(candidate, EvictCapacity)
+		}
+	}
+}
+
+func (this *tinyLFUCache[K, V]) overflow() bool {
+	return (this.capacity > 0 && this.size > this.capacity) ||
+		(this.maxCost > 0 && this.cost > this.maxCost)
+}
+
+func (this *tinyLFUCache[K, V]) windowOverflow() bool {
+	return (this.capacity > 0 && this.window.len > this.windowCap) ||
+		(this.maxCost > 0 && this.window.cost > this.windowCost)
+}
+
+func (this *tinyLFUCache[K, V]) protectedOverflow() bool {
+	if this.protected.len == 0 {
+		return false
+	}
+
+	return (this.capacity > 0 && this.protected.len > this.protectedCap) ||
+		(this.maxCost > 0 && this.protected.cost > this.protectedCost)
+}
+
+func (this *tinyLFUCache[K, V]) segment(node *cacheNode[K, V]) *nodeList[K, V] {
+	switch node.segment {
+	case segProbation:
+		return &this.probation
+	case segProtected:
+		return &this.protected
+	default:
+		return &this.window
+	}
+}
+
+func (l *nodeList[K, V]) init() {
+	var zeroK K
+	var zeroV V
+
+	l.head = createCacheNode(zeroK, zeroV, 0, time.Time{})
+	l.tail = createCacheNode(zeroK, zeroV, 0, time.Time{})
+	l.head.next = l.tail
+	l.tail.prev = l.head
+	l.len = 0
+	l.cost = 0
+}
+
+func (l *nodeList[K, V]) pushFront(node *cacheNode[K, V]) {
+	node.prev = l.head
+	node.next = l.head.next
+	l.head.next.prev = node
+	l.head.next = node
+	l.len++
+	l.cost += node.cost
+}
+
+func (l *nodeList[K, V]) remove(node *cacheNode[K, V]) {
+	node.prev.next = node.next
+	node.next.prev = node.prev
+	node.prev, node.next = nil, nil
+	l.len--
+	l.cost -= node.cost
+}
+
+func (l *nodeList[K, V]) moveToFront(node *cacheNode[K, V]) {
+	l.remove(node)
+	l.pushFront(node)
+}
+
+// back returns the least recently used node, or nil if l is empty.
+func (l *nodeList[K, V]) back() *cacheNode[K, V] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.tail.prev
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
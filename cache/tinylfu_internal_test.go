@@ -0,0 +1,23 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTinyLFUProtectedCost(t *testing.T) {
+	c := newTinyLFUCache[string, int](0, 100, hashBasicKey[string], false)
+	c.Put("a", 1, 1, time.Time{})
+	c.Put("b", 1, 1, time.Time{})
+	// a left the window for probation, the hit promotes it.
+	c.Get("a")
+	assert.Equal(t, segProtected, c.cache["a"].segment)
+
+	// the update raises the cost of a over the protected share.
+	c.Put("a", 2, 90, time.Time{})
+	assert.True(t, c.protected.cost <= c.protectedCost)
+	assert.Equal(t, segProbation, c.cache["a"].segment)
+	assert.Equal(t, int64(91), c.Cost())
+}
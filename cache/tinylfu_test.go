@@ -0,0 +1,164 @@
+package cache_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/cnzf1/gocore/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	traceKeys     = 100000
+	traceLength   = 200000
+	traceCapacity = 1000
+)
+
+func TestTinyLFU(t *testing.T) {
+	c := cache.NewLRU[string, int](2, cache.WithShards(1), cache.WithPolicy(cache.PolicyTinyLFU))
+	c.Put("a", 1)
+	c.Put("b", 2)
+
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	c.Put("c", 3)
+	assert.Equal(t, 2, c.Len())
+
+	assert.True(t, c.Remove("c"))
+	assert.Equal(t, 1, c.Len())
+	c.Purge()
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestTinyLFUAdmission(t *testing.T) {
+	evicted := make(map[int]cache.EvictReason)
+	c := cache.NewLRU[int, int](100, cache.WithShards(1), cache.WithPolicy(cache.PolicyTinyLFU),
+		cache.WithOnEvict(func(key int, value int, reason cache.EvictReason) {
+			evicted[key] = reason
+		}))
+
+	for i := 0; i < 50; i++ {
+		c.Put(i, i)
+	}
+	for n := 0; n < 10; n++ {
+		for i := 0; i < 50; i++ {
+			c.Get(i)
+		}
+	}
+
+	// a one-off scan does not flush the popular entries.
+	for i := 1000; i < 2000; i++ {
+		c.Put(i, i)
+	}
+	for i := 0; i < 50; i++ {
+		_, ok := c.Peek(i)
+		assert.True(t, ok, "key %d", i)
+	}
+	assert.Equal(t, 100, c.Len())
+	assert.Equal(t, 950, len(evicted))
+}
+
+func TestTinyLFUMaxCost(t *testing.T) {
+	c := cache.NewLRU[int, int](0, cache.WithMaxCost(100), cache.WithPolicy(cache.PolicyTinyLFU))
+	for i := 0; i < 1000; i++ {
+		c.PutWithCost(i, i, int64(i%10+1), 0)
+		assert.True(t, c.Cost() <= 100)
+	}
+	assert.False(t, c.PutWithCost(-1, -1, 101, 0))
+
+	// the update over the budget drops the stale value.
+	c.PutWithCost(-1, -1, 1, 0)
+	_, ok := c.Peek(-1)
+	assert.True(t, ok)
+	assert.False(t, c.PutWithCost(-1, -2, 101, 0))
+	_, ok = c.Peek(-1)
+	assert.False(t, ok)
+}
+
+func TestTinyLFUScanResistance(t *testing.T) {
+	trace := scanTrace(zipfTrace(1), 1)
+	lru := hitRatio(cache.PolicyLRU, trace)
+	lfu := hitRatio(cache.PolicyTinyLFU, trace)
+	assert.True(t, lfu > lru, "tinylfu: %.4f, lru: %.4f", lfu, lru)
+}
+
+func BenchmarkHitRatio(b *testing.B) {
+	traces := []struct {
+		name  string
+		trace []uint64
+	}{
+		{"zipf", zipfTrace(1)},
+		{"zipf+scan", scanTrace(zipfTrace(1), 1)},
+	}
+	policies := []struct {
+		name   string
+		policy cache.Policy
+	}{
+		{"lru", cache.PolicyLRU},
+		{"tinylfu", cache.PolicyTinyLFU},
+	}
+
+	for _, tr := range traces {
+		for _, p := range policies {
+			trace := tr.trace
+			policy := p.policy
+			b.Run(fmt.Sprintf("%s/%s", tr.name, p.name), func(b *testing.B) {
+				c := cache.NewLRU[uint64, uint64](traceCapacity, cache.WithPolicy(policy))
+				hits := 0
+				for i := 0; i < b.N; i++ {
+					key := trace[i%len(trace)]
+					if _, ok := c.Get(key); ok {
+						hits++
+					} else {
+						c.Put(key, key)
+					}
+				}
+				b.ReportMetric(float64(hits)*100/float64(b.N), "hit%")
+			})
+		}
+	}
+}
+
+func hitRatio(policy cache.Policy, trace []uint64) float64 {
+	c := cache.NewLRU[uint64, uint64](traceCapacity, cache.WithPolicy(policy))
+	hits := 0
+	for _, key := range trace {
+		if _, ok := c.Get(key); ok {
+			hits++
+		} else {
+			c.Put(key, key)
+		}
+	}
+	return float64(hits) / float64(len(trace))
+}
+
+func zipfTrace(seed int64) []uint64 {
+	r := rand.New(rand.NewSource(seed))
+	z := rand.NewZipf(r, 1.01, 1, traceKeys-1)
+	trace := make([]uint64, traceLength)
+	for i := range trace {
+		trace[i] = z.Uint64()
+	}
+	return trace
+}
+
+// scanTrace interleaves trace with sequential scans over keys never seen before.
+func scanTrace(trace []uint64, seed int64) []uint64 {
+	r := rand.New(rand.NewSource(seed))
+	next := uint64(traceKeys)
+	mixed := make([]uint64, 0, len(trace)*2)
+	for i, key := range trace {
+		mixed = append(mixed, key)
+		if i%1000 == 0 {
+			n := traceCapacity + r.Intn(traceCapacity)
+			for j := 0; j < n; j++ {
+				mixed = append(mixed, next)
+				next++
+			}
+		}
+	}
+	return mixed
+}
@@ -0,0 +1,315 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 22:48:05
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 22:48:05
+ * @Description:
+ */
+package codec
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// PaddingPKCS7 and PaddingZero are the paddings supported by AESCBC.
+const (
+	PaddingPKCS7 Padding = iota
+	PaddingZero
+)
+
+var (
+	ErrKeySize        = errors.New("invalid AES key size")
+	ErrCiphertext     = errors.New("ciphertext is too short or malformed")
+	ErrAuthentication = errors.New("message authentication failed")
+	ErrUnknownPadding = errors.New("unknown padding")
+)
+
+// the info of the MAC key derived by AESCBC from its encryption key.
+var cbcMACInfo = []byte("gocore aes-cbc hmac-sha256")
+
+type (
+	// Padding is the padding scheme of a block cipher mode.
+	Padding int
+
+	// AESGCM is a Crypter using AES in GCM mode, the random nonce is
+	// prepended to the ciphertext.
+	AESGCM struct {
+		aead cipher.AEAD
+		ad   []byte
+	}
+
+	// AESCBC is a Crypter using AES in CBC mode, the random IV is prepended
+	// to the ciphertext, and a HMAC-SHA256 of the IV, the ciphertext and the
+	// associated data is appended to it.
+	AESCBC struct {
+		block   cipher.Block
+		padding Padding
+		macKey  []byte
+		ad      []byte
+	}
+
+	aesConfig struct {
+		ad     []byte
+		macKey []byte
+	}
+
+	// AESOption customizes an AESGCM or an AESCBC.
+	AESOption func(*aesConfig)
+)
+
+// WithAssociatedData sets the data authenticated, but not encrypted, with every message.
+func WithAssociatedData(ad []byte) AESOption {
+	return func(c *aesConfig) {
+		c.ad = ad
+	}
+}
+
+// WithMACKey sets the key of the HMAC-SHA256 authenticating the messages of
+// AESCBC, which must differ from the encryption key. Without it, the MAC key
+// is derived from the encryption key with HKDF. It's ignored by AESGCM.
+func WithMACKey(key []byte) AESOption {
+	return func(c *aesConfig) {
+		c.macKey = key
+	}
+}
+
+// NewAESGCM returns an AESGCM, key must be 16, 24 or 32 bytes long.
+func NewAESGCM(key []byte, opts ...AESOption) (*AESGCM, error) {
+	cfg := newAESConfig(opts...)
+
+	block, err := newAESBlock(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AESGCM{
+		aead: aead,
+		ad:   cfg.ad,
+	}, nil
+}
+
+// Encrypt encrypts rawData with the associated data set by WithAssociatedData.
+func (this *AESGCM) Encrypt(rawData []byte) ([]byte, error) {
+	return this.EncryptWithAD(rawData, this.ad)
+}
+
+// EncryptWithAD encrypts rawData and authenticates it along with ad.
+func (this *AESGCM) EncryptWithAD(rawData, ad []byte) ([]byte, error) {
+	nonceSize := this.aead.NonceSize()
+	out := make([]byte, nonceSize, nonceSize+len(rawData)+this.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, out); err != nil {
+		return nil, err
+	}
+
+	return this.aead.Seal(out, out, rawData, ad), nil
+}
+
+// Decrypt decrypts encData with the associated data set by WithAssociatedData.
+func (this *AESGCM) Decrypt(encData []byte) ([]byte, error) {
+	return this.DecryptWithAD(encData, this.ad)
+}
+
+// DecryptWithAD decrypts encData, it returns ErrAuthentication if encData or ad was tampered.
+func (this *AESGCM) DecryptWithAD(encData, ad []byte) ([]byte, error) {
+	nonceSize := this.aead.NonceSize()
+	if len(encData) < nonceSize+this.aead.Overhead() {
+		return nil, ErrCiphertext
+	}
+
+	out, err := this.aead.Open(nil, encData[:nonceSize], encData[nonceSize:], ad)
+	if err != nil {
+		return nil, ErrAuthentication
+	}
+	return out, nil
+}
+
+func (this *AESGCM) EncryptBase64(rawData []byte) (string, error) {
+	return encryptBase64(this, rawData)
+}
+
+func (this *AESGCM) DecryptBase64(encData string) ([]byte, error) {
+	return decryptBase64(this, encData)
+}
+
+// NewAESCBC returns an AESCBC, key must be 16, 24 or 32 bytes long.
+func NewAESCBC(key []byte, padding Padding, opts ...AESOption) (*AESCBC, error) {
+	cfg := newAESConfig(opts...)
+
+	if padding != PaddingPKCS7 && padding != PaddingZero {
+		return nil, ErrUnknownPadding
+	}
+
+	block, err := newAESBlock(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.macKey) == 0 {
+		cfg.macKey = make([]byte, sha256.Size)
+		if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, cbcMACInfo), cfg.macKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return &AESCBC{
+		block:   block,
+		padding: padding,
+		macKey:  cfg.macKey,
+		ad:      cfg.ad,
+	}, nil
+}
+
+func (this *AESCBC) Encrypt(rawData []byte) ([]byte, error) {
+	blockSize := this.block.BlockSize()
+	padded := pad(rawData, blockSize, this.padding)
+
+	out := make([]byte, blockSize+len(padded), blockSize+len(padded)+sha256.Size)
+	iv := out[:blockSize]
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+
+	cipher.NewCBCEncrypter(this.block, iv).CryptBlocks(out[blockSize:], padded)
+	return append(out, this.mac(out)...), nil
+}
+
+// Decrypt decrypts encData, it returns ErrAuthentication on any failure, so
+// that the failures tell nothing about the plaintext.
+func (this *AESCBC) Decrypt(encData []byte) ([]byte, error) {
+	if len(encData) < sha256.Size {
+		return nil, ErrAuthentication
+	}
+
+	tag := encData[len(encData)-sha256.Size:]
+	encData = encData[:len(encData)-sha256.Size]
+	if !hmac.Equal(tag, this.mac(encData)) {
+		return nil, ErrAuthentication
+	}
+
+	// only the zero padding can leave an empty message without any block.
+	blockSize := this.block.BlockSize()
+	minSize := 2 * blockSize
+	if this.padding == PaddingZero {
+		minSize = blockSize
+	}
+	if len(encData) < minSize || len(encData)%blockSize != 0 {
+		return nil, ErrAuthentication
+	}
+
+	out := make([]byte, len(encData)-blockSize)
+	cipher.NewCBCDecrypter(this.block, encData[:blockSize]).CryptBlocks(out, encData[blockSize:])
+	out, ok := unpad(out, blockSize, this.padding)
+	if !ok {
+		return nil, ErrAuthentication
+	}
+	return out, nil
+}
+
+func (this *AESCBC) EncryptBase64(rawData []byte) (string, error) {
+	return encryptBase64(this, rawData)
+}
+
+func (this *AESCBC) DecryptBase64(encData string) ([]byte, error) {
+	return decryptBase64(this, encData)
+}
+
+// mac returns the HMAC-SHA256 of data and the associated data, the length of
+// the associated data is included so that the boundary can not be moved.
+func (this *AESCBC) mac(data []byte) []byte {
+	h := hmac.New(sha256.New, this.macKey)
+	h.Write(data)
+	h.Write(this.ad)
+
+	var size [8]byte
+	binary.BigEndian.PutUint64(size[:], uint64(len(this.ad)))
+	h.Write(size[:])
+	return h.Sum(nil)
+}
+
+func newAESConfig(opts ...AESOption) *aesConfig {
+	cfg := &aesConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+func newAESBlock(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 16, 24, 32:
+		return aes.NewCipher(key)
+	default:
+		return nil, ErrKeySize
+	}
+}
+
+// pad pads data to a multiple of blockSize, PaddingZero does not pad the data
+// already aligned, so the trailing zeros of the data are lost on unpad.
+func pad(data []byte, blockSize int, padding Padding) []byte {
+	n := blockSize - len(data)%blockSize
+	switch padding {
+	case PaddingZero:
+		if n == blockSize {
+			n = 0
+		}
+		return append(append(make([]byte, 0, len(data)+n), data...), make([]byte, n)...)
+	default:
+		return append(append(make([]byte, 0, len(data)+n), data...), bytes.Repeat([]byte{byte(n)}, n)...)
+	}
+}
+
+func unpad(data []byte, blockSize int, padding Padding) ([]byte, bool) {
+	switch padding {
+	case PaddingZero:
+		return bytes.TrimRight(data, "\x00"), true
+	default:
+		if len(data) == 0 {
+			return nil, false
+		}
+
+		n := int(data[len(data)-1])
+		if n == 0 || n > blockSize || n > len(data) {
+			return nil, false
+		}
+		for _, b := range data[len(data)-n:] {
+			if int(b) != n {
+				return nil, false
+			}
+		}
+		return data[:len(data)-n], true
+	}
+}
+
+func encryptBase64(e Encrypter, rawData []byte) (string, error) {
+	enc, err := e.Encrypt(rawData)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(enc), nil
+}
+
+func decryptBase64(d Decrypter, encData string) ([]byte, error) {
+	dec, err := base64.StdEncoding.DecodeString(encData)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Decrypt(dec)
+}
@@ -0,0 +1,110 @@
+package codec_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAESGCM(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	var c codec.Crypter
+	c, err := codec.NewAESGCM(key, codec.WithAssociatedData([]byte("header")))
+	assert.Nil(t, err)
+
+	enc, err := c.Encrypt([]byte(testBody))
+	assert.Nil(t, err)
+	enc2, err := c.Encrypt([]byte(testBody))
+	assert.Nil(t, err)
+	assert.NotEqual(t, enc, enc2)
+
+	dec, err := c.Decrypt(enc)
+	assert.Nil(t, err)
+	assert.Equal(t, testBody, string(dec))
+
+	b64, err := c.EncryptBase64([]byte(testBody))
+	assert.Nil(t, err)
+	dec, err = c.DecryptBase64(b64)
+	assert.Nil(t, err)
+	assert.Equal(t, testBody, string(dec))
+
+	enc[len(enc)-1] ^= 1
+	_, err = c.Decrypt(enc)
+	assert.Equal(t, codec.ErrAuthentication, err)
+	_, err = c.Decrypt(enc[:4])
+	assert.Equal(t, codec.ErrCiphertext, err)
+
+	other, err := codec.NewAESGCM(key, codec.WithAssociatedData([]byte("other")))
+	assert.Nil(t, err)
+	_, err = other.Decrypt(enc2)
+	assert.Equal(t, codec.ErrAuthentication, err)
+
+	_, err = codec.NewAESGCM([]byte("short"))
+	assert.Equal(t, codec.ErrKeySize, err)
+}
+
+func TestAESCBC(t *testing.T) {
+	key := bytes.Repeat([]byte{2}, 16)
+	for _, padding := range []codec.Padding{codec.PaddingPKCS7, codec.PaddingZero} {
+		c, err := codec.NewAESCBC(key, padding)
+		assert.Nil(t, err)
+
+		for _, body := range []string{"", testBody, "0123456789abcdef"} {
+			enc, err := c.Encrypt([]byte(body))
+			assert.Nil(t, err)
+			assert.Equal(t, 0, (len(enc)-32)%16)
+
+			dec, err := c.Decrypt(enc)
+			assert.Nil(t, err)
+			assert.Equal(t, body, string(dec))
+		}
+	}
+
+	c, err := codec.NewAESCBC(key, codec.PaddingPKCS7)
+	assert.Nil(t, err)
+	_, err = c.Decrypt(make([]byte, 15))
+	assert.Equal(t, codec.ErrAuthentication, err)
+
+	// without a MAC key, the tampered messages are still rejected.
+	enc, err := c.Encrypt([]byte(testBody))
+	assert.Nil(t, err)
+	for _, i := range []int{0, 16, len(enc) - 33, len(enc) - 1} {
+		enc[i] ^= 1
+		_, err = c.Decrypt(enc)
+		assert.Equal(t, codec.ErrAuthentication, err)
+		enc[i] ^= 1
+	}
+	_, err = c.Decrypt(enc[:len(enc)-1])
+	assert.Equal(t, codec.ErrAuthentication, err)
+
+	_, err = codec.NewAESCBC(key, codec.Padding(10))
+	assert.Equal(t, codec.ErrUnknownPadding, err)
+}
+
+func TestAESCBCWithMAC(t *testing.T) {
+	key := bytes.Repeat([]byte{3}, 24)
+	macKey := bytes.Repeat([]byte{4}, 32)
+	c, err := codec.NewAESCBC(key, codec.PaddingPKCS7, codec.WithMACKey(macKey),
+		codec.WithAssociatedData([]byte("header")))
+	assert.Nil(t, err)
+
+	b64, err := c.EncryptBase64([]byte(testBody))
+	assert.Nil(t, err)
+	dec, err := c.DecryptBase64(b64)
+	assert.Nil(t, err)
+	assert.Equal(t, testBody, string(dec))
+
+	enc, err := c.Encrypt([]byte(testBody))
+	assert.Nil(t, err)
+	enc[0] ^= 1
+	_, err = c.Decrypt(enc)
+	assert.Equal(t, codec.ErrAuthentication, err)
+
+	other, err := codec.NewAESCBC(key, codec.PaddingPKCS7, codec.WithMACKey(macKey))
+	assert.Nil(t, err)
+	enc[0] ^= 1
+	_, err = other.Decrypt(enc)
+	assert.Equal(t, codec.ErrAuthentication, err)
+}
@@ -3,13 +3,13 @@ package codec
 import "crypto"
 
 type (
-	// Decrypter represents a RSA decrypter.
+	// Decrypter represents a decrypter, such as Rsa, AESGCM or AESCBC.
 	Decrypter interface {
 		Decrypt(encData []byte) ([]byte, error)
 		DecryptBase64(encData string) ([]byte, error)
 	}
 
-	// Encrypter represents a RSA encrypter.
+	// Encrypter represents an encrypter, such as Rsa, AESGCM or AESCBC.
 	Encrypter interface {
 		Encrypt(rawData []byte) ([]byte, error)
 		EncryptBase64(rawData []byte) (string, error)
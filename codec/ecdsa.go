@@ -0,0 +1,236 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 23:21:37
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 23:21:37
+ * @Description:
+ */
+package codec
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrPrivKeyNotEcdsa = errors.New("private key type is not ECDSA")
+	ErrPubKeyNotEcdsa  = errors.New("public key type is not ECDSA")
+	ErrCurve           = errors.New("unsupported elliptic curve")
+	ErrHashUnavailable = errors.New("hash algorithm is not available")
+)
+
+// 签名格式
+const (
+	// ASN.1 DER编码的签名, 与openssl一致
+	EcdsaASN1 EcdsaFormat = iota
+	// r||s定长拼接的签名, 与JWS(ES256/ES384)一致
+	EcdsaRaw
+)
+
+type (
+	EcdsaFormat int
+
+	Ecdsa struct {
+		privateKey   string
+		publicKey    string
+		ecPrivateKey *ecdsa.PrivateKey
+		ecPublicKey  *ecdsa.PublicKey
+		format       EcdsaFormat
+		passphrase   []byte
+	}
+
+	EcdsaOption func(*Ecdsa)
+)
+
+// 设置签名格式, 默认为ASN.1
+func WithEcdsaFormat(format EcdsaFormat) EcdsaOption {
+	return func(e *Ecdsa) {
+		e.format = format
+	}
+}
+
+// 设置加密的pkcs8格式私钥的口令
+func WithEcdsaPassphrase(passphrase []byte) EcdsaOption {
+	return func(e *Ecdsa) {
+		e.passphrase = passphrase
+	}
+}
+
+// 生成ECDSA对象, 私钥支持sec1和pkcs8格式, 公钥为pkix格式, 缺少公钥时由私钥导出
+func NewEcdsa(publicKey, privateKey string, opts ...EcdsaOption) (*Ecdsa, error) {
+	ecObj := &Ecdsa{
+		privateKey: privateKey,
+		publicKey:  publicKey,
+	}
+	for _, opt := range opts {
+		opt(ecObj)
+	}
+
+	err := ecObj.init()
+	return ecObj, err
+}
+
+// 生成sec1格式私钥和pkix格式公钥, 支持P-256, P-384和P-521
+func CreateEcdsa(curve elliptic.Curve, opts ...EcdsaOption) (*Ecdsa, error) {
+	ecPrivateKey, err := generateEcdsaKey(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	derSec1, err := x509.MarshalECPrivateKey(ecPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	derPkix, err := x509.MarshalPKIXPublicKey(&ecPrivateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEcdsa(encodePEM(pemPublicKey, derPkix), encodePEM(pemECPrivateKey, derSec1), opts...)
+}
+
+// 生成pkcs8格式私钥和pkix格式公钥, 支持P-256, P-384和P-521
+func CreateEcdsaPkcs8(curve elliptic.Curve, opts ...EcdsaOption) (*Ecdsa, error) {
+	ecPrivateKey, err := generateEcdsaKey(curve)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, publicKey, err := encodeKeyPairPEM(ecPrivateKey, &ecPrivateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEcdsa(publicKey, privateKey, opts...)
+}
+
+func (this *Ecdsa) init() error {
+	if this.privateKey != "" {
+		pemKey := this.privateKey
+		if strings.Contains(pemKey, "BEGIN "+pemEncryptedPrivateKey) {
+			var err error
+			if pemKey, err = DecryptPrivateKeyPEM(pemKey, this.passphrase); err != nil {
+				return err
+			}
+		}
+
+		key, err := parsePrivateKeyPEM(pemKey)
+		if err != nil {
+			return ErrPrivKey
+		}
+
+		var ok bool
+		if this.ecPrivateKey, ok = key.(*ecdsa.PrivateKey); !ok {
+			return ErrPrivKeyNotEcdsa
+		}
+	}
+
+	if this.publicKey != "" {
+		key, err := parsePublicKeyPEM(this.publicKey)
+		if err != nil {
+			return ErrPubKey
+		}
+
+		var ok bool
+		if this.ecPublicKey, ok = key.(*ecdsa.PublicKey); !ok {
+			return ErrPubKeyNotEcdsa
+		}
+	}
+
+	if this.ecPublicKey == nil && this.ecPrivateKey != nil {
+		this.ecPublicKey = &this.ecPrivateKey.PublicKey
+		der, err := x509.MarshalPKIXPublicKey(this.ecPublicKey)
+		if err != nil {
+			return err
+		}
+		this.publicKey = encodePEM(pemPublicKey, der)
+	}
+	return nil
+}
+
+// PEM格式私钥
+func (this *Ecdsa) PrivateKey() string {
+	return this.privateKey
+}
+
+// PEM格式公钥
+func (this *Ecdsa) PublicKey() string {
+	return this.publicKey
+}
+
+// 私钥签名, algorithmSign为0时rawData即为摘要
+func (this *Ecdsa) Sign(rawData []byte, algorithmSign crypto.Hash) ([]byte, error) {
+	if this.ecPrivateKey == nil {
+		return nil, ErrNoPrivKey
+	}
+	if algorithmSign > 0 && !algorithmSign.Available() {
+		return nil, ErrHashUnavailable
+	}
+
+	digest := hashData(rawData, algorithmSign)
+	if this.format == EcdsaRaw {
+		r, s, err := ecdsa.Sign(rand.Reader, this.ecPrivateKey, digest)
+		if err != nil {
+			return nil, err
+		}
+
+		size := curveByteSize(this.ecPrivateKey.Curve)
+		sign := make([]byte, 2*size)
+		r.FillBytes(sign[:size])
+		s.FillBytes(sign[size:])
+		return sign, nil
+	}
+
+	return ecdsa.SignASN1(rand.Reader, this.ecPrivateKey, digest)
+}
+
+// 公钥验签
+func (this *Ecdsa) Verify(rawData []byte, sign []byte, algorithmSign crypto.Hash) bool {
+	if this.ecPublicKey == nil || (algorithmSign > 0 && !algorithmSign.Available()) {
+		return false
+	}
+
+	digest := hashData(rawData, algorithmSign)
+	if this.format == EcdsaRaw {
+		size := curveByteSize(this.ecPublicKey.Curve)
+		if len(sign) != 2*size {
+			return false
+		}
+
+		r := new(big.Int).SetBytes(sign[:size])
+		s := new(big.Int).SetBytes(sign[size:])
+		return ecdsa.Verify(this.ecPublicKey, digest, r, s)
+	}
+
+	return ecdsa.VerifyASN1(this.ecPublicKey, digest, sign)
+}
+
+func generateEcdsaKey(curve elliptic.Curve) (*ecdsa.PrivateKey, error) {
+	switch curve {
+	case elliptic.P256(), elliptic.P384(), elliptic.P521():
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	default:
+		return nil, ErrCurve
+	}
+}
+
+func curveByteSize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
+
+func hashData(rawData []byte, algorithmSign crypto.Hash) []byte {
+	if algorithmSign == 0 {
+		return rawData
+	}
+
+	hash := algorithmSign.New()
+	hash.Write(rawData)
+	return hash.Sum(nil)
+}
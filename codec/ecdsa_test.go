@@ -0,0 +1,98 @@
+package codec_test
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"strings"
+	"testing"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEcdsa(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384()} {
+		for _, format := range []codec.EcdsaFormat{codec.EcdsaASN1, codec.EcdsaRaw} {
+			var signer codec.Signer
+			ecObj, err := codec.CreateEcdsa(curve, codec.WithEcdsaFormat(format))
+			assert.Nil(t, err)
+			signer = ecObj
+
+			sign, err := signer.Sign([]byte(testBody), crypto.SHA256)
+			assert.Nil(t, err)
+			if format == codec.EcdsaRaw {
+				assert.Equal(t, 2*((curve.Params().BitSize+7)/8), len(sign))
+			}
+			assert.True(t, signer.Verify([]byte(testBody), sign, crypto.SHA256))
+			assert.False(t, signer.Verify([]byte("other"), sign, crypto.SHA256))
+
+			// the verifier only needs the public key.
+			verifier, err := codec.NewEcdsa(ecObj.PublicKey(), "", codec.WithEcdsaFormat(format))
+			assert.Nil(t, err)
+			assert.True(t, verifier.Verify([]byte(testBody), sign, crypto.SHA256))
+			_, err = verifier.Sign([]byte(testBody), crypto.SHA256)
+			assert.Equal(t, codec.ErrNoPrivKey, err)
+
+			// MD4 isn't linked in.
+			_, err = signer.Sign([]byte(testBody), crypto.MD4)
+			assert.Equal(t, codec.ErrHashUnavailable, err)
+		}
+	}
+}
+
+func TestEcdsaPkcs8(t *testing.T) {
+	ecObj, err := codec.CreateEcdsaPkcs8(elliptic.P256())
+	assert.Nil(t, err)
+	assert.Contains(t, ecObj.PrivateKey(), "BEGIN PRIVATE KEY")
+
+	loaded, err := codec.NewEcdsa("", ecObj.PrivateKey())
+	assert.Nil(t, err)
+	sign, err := loaded.Sign([]byte(testBody), crypto.SHA256)
+	assert.Nil(t, err)
+	assert.True(t, ecObj.Verify([]byte(testBody), sign, crypto.SHA256))
+
+	_, err = codec.CreateEcdsa(elliptic.P224())
+	assert.Equal(t, codec.ErrCurve, err)
+	_, err = codec.NewEcdsa(pubKey, "")
+	assert.Equal(t, codec.ErrPubKeyNotEcdsa, err)
+}
+
+func TestEd25519(t *testing.T) {
+	var signer codec.Signer
+	edObj, err := codec.CreateEd25519()
+	assert.Nil(t, err)
+	signer = edObj
+
+	sign, err := signer.Sign([]byte(testBody), 0)
+	assert.Nil(t, err)
+	assert.True(t, signer.Verify([]byte(testBody), sign, 0))
+	assert.False(t, signer.Verify([]byte("other"), sign, 0))
+
+	_, err = signer.Sign([]byte(testBody), crypto.SHA256)
+	assert.Equal(t, codec.ErrEd25519Hash, err)
+
+	verifier, err := codec.NewEd25519(edObj.PublicKey(), "")
+	assert.Nil(t, err)
+	assert.True(t, verifier.Verify([]byte(testBody), sign, 0))
+
+	_, err = codec.NewEd25519("", priKey)
+	assert.Equal(t, codec.ErrPrivKeyNotEd25519, err)
+}
+
+func TestDerivePublicKey(t *testing.T) {
+	ecObj, err := codec.CreateEcdsa(elliptic.P256())
+	assert.Nil(t, err)
+	loaded, err := codec.NewEcdsa("", ecObj.PrivateKey())
+	assert.Nil(t, err)
+	assert.Equal(t, ecObj.PublicKey(), loaded.PublicKey())
+
+	edObj, err := codec.CreateEd25519()
+	assert.Nil(t, err)
+	edLoaded, err := codec.NewEd25519("", edObj.PrivateKey())
+	assert.Nil(t, err)
+	assert.Equal(t, edObj.PublicKey(), edLoaded.PublicKey())
+
+	rsaObj, err := codec.NewRsa("", priKey)
+	assert.Nil(t, err)
+	assert.Equal(t, pubKey, strings.TrimSpace(rsaObj.PublicKey()))
+}
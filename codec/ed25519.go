@@ -0,0 +1,146 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 23:21:37
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 23:21:37
+ * @Description:
+ */
+package codec
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrPrivKeyNotEd25519 = errors.New("private key type is not Ed25519")
+	ErrPubKeyNotEd25519  = errors.New("public key type is not Ed25519")
+	ErrEd25519Hash       = errors.New("Ed25519 signs the message itself, the hash algorithm must be 0")
+)
+
+type (
+	Ed25519 struct {
+		privateKey   string
+		publicKey    string
+		edPrivateKey ed25519.PrivateKey
+		edPublicKey  ed25519.PublicKey
+		passphrase   []byte
+	}
+
+	Ed25519Option func(*Ed25519)
+)
+
+// 设置加密的pkcs8格式私钥的口令
+func WithEd25519Passphrase(passphrase []byte) Ed25519Option {
+	return func(e *Ed25519) {
+		e.passphrase = passphrase
+	}
+}
+
+// 生成Ed25519对象, 私钥为pkcs8格式, 公钥为pkix格式, 缺少公钥时由私钥导出
+func NewEd25519(publicKey, privateKey string, opts ...Ed25519Option) (*Ed25519, error) {
+	edObj := &Ed25519{
+		privateKey: privateKey,
+		publicKey:  publicKey,
+	}
+	for _, opt := range opts {
+		opt(edObj)
+	}
+
+	err := edObj.init()
+	return edObj, err
+}
+
+// 生成pkcs8格式私钥和pkix格式公钥
+func CreateEd25519(opts ...Ed25519Option) (*Ed25519, error) {
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, publicKey, err := encodeKeyPairPEM(edPrivateKey, edPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEd25519(publicKey, privateKey, opts...)
+}
+
+func (this *Ed25519) init() error {
+	if this.privateKey != "" {
+		pemKey := this.privateKey
+		if strings.Contains(pemKey, "BEGIN "+pemEncryptedPrivateKey) {
+			var err error
+			if pemKey, err = DecryptPrivateKeyPEM(pemKey, this.passphrase); err != nil {
+				return err
+			}
+		}
+
+		key, err := parsePrivateKeyPEM(pemKey)
+		if err != nil {
+			return ErrPrivKey
+		}
+
+		var ok bool
+		if this.edPrivateKey, ok = key.(ed25519.PrivateKey); !ok {
+			return ErrPrivKeyNotEd25519
+		}
+	}
+
+	if this.publicKey != "" {
+		key, err := parsePublicKeyPEM(this.publicKey)
+		if err != nil {
+			return ErrPubKey
+		}
+
+		var ok bool
+		if this.edPublicKey, ok = key.(ed25519.PublicKey); !ok {
+			return ErrPubKeyNotEd25519
+		}
+	}
+
+	if this.edPublicKey == nil && this.edPrivateKey != nil {
+		this.edPublicKey = this.edPrivateKey.Public().(ed25519.PublicKey)
+		der, err := x509.MarshalPKIXPublicKey(this.edPublicKey)
+		if err != nil {
+			return err
+		}
+		this.publicKey = encodePEM(pemPublicKey, der)
+	}
+	return nil
+}
+
+// PEM格式私钥
+func (this *Ed25519) PrivateKey() string {
+	return this.privateKey
+}
+
+// PEM格式公钥
+func (this *Ed25519) PublicKey() string {
+	return this.publicKey
+}
+
+// 私钥签名, Ed25519自身对消息做摘要, algorithmSign必须为0
+func (this *Ed25519) Sign(rawData []byte, algorithmSign crypto.Hash) ([]byte, error) {
+	if this.edPrivateKey == nil {
+		return nil, ErrNoPrivKey
+	}
+	if algorithmSign != 0 {
+		return nil, ErrEd25519Hash
+	}
+
+	return ed25519.Sign(this.edPrivateKey, rawData), nil
+}
+
+// 公钥验签
+func (this *Ed25519) Verify(rawData []byte, sign []byte, algorithmSign crypto.Hash) bool {
+	if this.edPublicKey == nil || algorithmSign != 0 {
+		return false
+	}
+
+	return ed25519.Verify(this.edPublicKey, rawData, sign)
+}
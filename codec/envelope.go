@@ -0,0 +1,239 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 23:44:12
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 23:44:12
+ * @Description:
+ */
+package codec
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// EnvelopeVersion is the version of the envelope format written by Envelope.
+const EnvelopeVersion = 1
+
+// EnvelopeAES256GCM and EnvelopeAES128GCM are the algorithms encrypting the payload.
+const (
+	EnvelopeAES256GCM EnvelopeAlgorithm = iota + 1
+	EnvelopeAES128GCM
+)
+
+const maxEnvelopeField = 1 << 16
+
+var (
+	ErrEnvelopeFormat    = errors.New("malformed envelope")
+	ErrEnvelopeVersion   = errors.New("unsupported envelope version")
+	ErrEnvelopeAlgorithm = errors.New("unsupported envelope algorithm")
+	ErrEnvelopeKeyID     = errors.New("envelope was sealed with another key")
+)
+
+type (
+	// EnvelopeAlgorithm is the algorithm encrypting the payload of an envelope.
+	EnvelopeAlgorithm uint8
+
+	// EnvelopeHeader describes how an envelope was sealed.
+	EnvelopeHeader struct {
+		Version   uint8
+		Algorithm EnvelopeAlgorithm
+		// KeyID identifies the key encryption key which wrapped the data key.
+		KeyID string
+		// WrappedKey is the data key encrypted by the key encryption key.
+		WrappedKey []byte
+	}
+
+	// Envelope is a Crypter using envelope encryption, every message is encrypted
+	// with a random data key, which is wrapped by the key encryption key and
+	// stored in the header of the message.
+	//
+	// The message is made of the version, the algorithm, the key id and the wrapped
+	// data key, followed by the nonce, the ciphertext and the tag of AES-GCM. The
+	// header is authenticated as the associated data of AES-GCM.
+	Envelope struct {
+		kek       Crypter
+		keyID     string
+		algorithm EnvelopeAlgorithm
+	}
+
+	// EnvelopeOption customizes an Envelope.
+	EnvelopeOption func(*Envelope)
+)
+
+// WithEnvelopeAlgorithm sets the algorithm encrypting the payload, EnvelopeAES256GCM by default.
+func WithEnvelopeAlgorithm(algorithm EnvelopeAlgorithm) EnvelopeOption {
+	return func(e *Envelope) {
+		e.algorithm = algorithm
+	}
+}
+
+// NewEnvelope returns an Envelope wrapping the data keys with kek, keyID is
+// written in the header so that the reader can pick the matching key.
+func NewEnvelope(kek Crypter, keyID string, opts ...EnvelopeOption) (*Envelope, error) {
+	e := &Envelope{
+		kek:       kek,
+		keyID:     keyID,
+		algorithm: EnvelopeAES256GCM,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.algorithm.keySize() == 0 {
+		return nil, ErrEnvelopeAlgorithm
+	}
+	if len(keyID) >= maxEnvelopeField {
+		return nil, ErrEnvelopeFormat
+	}
+	return e, nil
+}
+
+// KeyID returns the id of the key encryption key.
+func (this *Envelope) KeyID() string {
+	return this.keyID
+}
+
+func (this *Envelope) Encrypt(rawData []byte) ([]byte, error) {
+	dataKey := make([]byte, this.algorithm.keySize())
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+
+	wrapped, err := this.kek.Encrypt(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) >= maxEnvelopeField {
+		return nil, ErrEnvelopeFormat
+	}
+
+	header := EnvelopeHeader{
+		Version:    EnvelopeVersion,
+		Algorithm:  this.algorithm,
+		KeyID:      this.keyID,
+		WrappedKey: wrapped,
+	}.marshal()
+
+	aead, err := NewAESGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := aead.EncryptWithAD(rawData, header)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, enc...), nil
+}
+
+// Decrypt decrypts encData, it returns ErrEnvelopeKeyID if encData was sealed
+// with another key id.
+func (this *Envelope) Decrypt(encData []byte) ([]byte, error) {
+	header, n, err := parseEnvelopeHeader(encData)
+	if err != nil {
+		return nil, err
+	}
+	if header.KeyID != this.keyID {
+		return nil, ErrEnvelopeKeyID
+	}
+
+	dataKey, err := this.kek.Decrypt(header.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataKey) != header.Algorithm.keySize() {
+		return nil, ErrEnvelopeFormat
+	}
+
+	aead, err := NewAESGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return aead.DecryptWithAD(encData[n:], encData[:n])
+}
+
+func (this *Envelope) EncryptBase64(rawData []byte) (string, error) {
+	return encryptBase64(this, rawData)
+}
+
+func (this *Envelope) DecryptBase64(encData string) ([]byte, error) {
+	return decryptBase64(this, encData)
+}
+
+// ParseEnvelopeHeader returns the header of an envelope, it's meant to pick
+// the key encryption key by its id before decrypting.
+func ParseEnvelopeHeader(encData []byte) (EnvelopeHeader, error) {
+	header, _, err := parseEnvelopeHeader(encData)
+	return header, err
+}
+
+func (a EnvelopeAlgorithm) keySize() int {
+	switch a {
+	case EnvelopeAES256GCM:
+		return 32
+	case EnvelopeAES128GCM:
+		return 16
+	default:
+		return 0
+	}
+}
+
+func (h EnvelopeHeader) marshal() []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 6+len(h.KeyID)+len(h.WrappedKey)))
+	buf.WriteByte(h.Version)
+	buf.WriteByte(byte(h.Algorithm))
+
+	var size [2]byte
+	binary.BigEndian.PutUint16(size[:], uint16(len(h.KeyID)))
+	buf.Write(size[:])
+	buf.WriteString(h.KeyID)
+	binary.BigEndian.PutUint16(size[:], uint16(len(h.WrappedKey)))
+	buf.Write(size[:])
+	buf.Write(h.WrappedKey)
+	return buf.Bytes()
+}
+
+// parseEnvelopeHeader returns the header of encData and its length.
+func parseEnvelopeHeader(encData []byte) (h EnvelopeHeader, n int, err error) {
+	if len(encData) < 2 {
+		return h, 0, ErrEnvelopeFormat
+	}
+
+	h.Version = encData[0]
+	if h.Version != EnvelopeVersion {
+		return h, 0, ErrEnvelopeVersion
+	}
+	h.Algorithm = EnvelopeAlgorithm(encData[1])
+	if h.Algorithm.keySize() == 0 {
+		return h, 0, ErrEnvelopeAlgorithm
+	}
+	n = 2
+
+	keyID, n, err := readEnvelopeField(encData, n)
+	if err != nil {
+		return h, 0, err
+	}
+	h.KeyID = string(keyID)
+
+	if h.WrappedKey, n, err = readEnvelopeField(encData, n); err != nil {
+		return h, 0, err
+	}
+	return h, n, nil
+}
+
+func readEnvelopeField(data []byte, offset int) ([]byte, int, error) {
+	if len(data) < offset+2 {
+		return nil, 0, ErrEnvelopeFormat
+	}
+
+	size := int(binary.BigEndian.Uint16(data[offset:]))
+	offset += 2
+	if len(data) < offset+size {
+		return nil, 0, ErrEnvelopeFormat
+	}
+	return data[offset : offset+size], offset + size, nil
+}
@@ -0,0 +1,72 @@
+package codec_test
+
+import (
+	"bytes"
+	"crypto"
+	"testing"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelope(t *testing.T) {
+	kek, err := codec.NewRsa(pubKey, priKey, codec.WithOAEP(crypto.SHA256, nil))
+	assert.Nil(t, err)
+	var env codec.Crypter
+	env, err = codec.NewEnvelope(kek, "rsa-2024")
+	assert.Nil(t, err)
+
+	payload := bytes.Repeat([]byte(testBody), 10000)
+	enc, err := env.Encrypt(payload)
+	assert.Nil(t, err)
+	// a single RSA block wraps the data key.
+	assert.True(t, len(enc) < len(payload)+256+64)
+
+	header, err := codec.ParseEnvelopeHeader(enc)
+	assert.Nil(t, err)
+	assert.Equal(t, uint8(codec.EnvelopeVersion), header.Version)
+	assert.Equal(t, codec.EnvelopeAES256GCM, header.Algorithm)
+	assert.Equal(t, "rsa-2024", header.KeyID)
+	assert.Equal(t, 256, len(header.WrappedKey))
+
+	dec, err := env.Decrypt(enc)
+	assert.Nil(t, err)
+	assert.Equal(t, payload, dec)
+
+	b64, err := env.EncryptBase64([]byte(testBody))
+	assert.Nil(t, err)
+	dec, err = env.DecryptBase64(b64)
+	assert.Nil(t, err)
+	assert.Equal(t, testBody, string(dec))
+}
+
+func TestEnvelopeTampered(t *testing.T) {
+	kek, err := codec.NewAESGCM(bytes.Repeat([]byte{1}, 32))
+	assert.Nil(t, err)
+	env, err := codec.NewEnvelope(kek, "k1", codec.WithEnvelopeAlgorithm(codec.EnvelopeAES128GCM))
+	assert.Nil(t, err)
+
+	enc, err := env.Encrypt([]byte(testBody))
+	assert.Nil(t, err)
+
+	other, err := codec.NewEnvelope(kek, "k2")
+	assert.Nil(t, err)
+	_, err = other.Decrypt(enc)
+	assert.Equal(t, codec.ErrEnvelopeKeyID, err)
+
+	tampered := append([]byte{}, enc...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = env.Decrypt(tampered)
+	assert.Equal(t, codec.ErrAuthentication, err)
+
+	tampered = append([]byte{}, enc...)
+	tampered[0] = 2
+	_, err = env.Decrypt(tampered)
+	assert.Equal(t, codec.ErrEnvelopeVersion, err)
+
+	_, err = env.Decrypt(enc[:5])
+	assert.Equal(t, codec.ErrEnvelopeFormat, err)
+
+	_, err = codec.NewEnvelope(kek, "k1", codec.WithEnvelopeAlgorithm(9))
+	assert.Equal(t, codec.ErrEnvelopeAlgorithm, err)
+}
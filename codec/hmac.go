@@ -0,0 +1,49 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 00:41:26
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 00:41:26
+ * @Description:
+ */
+package codec
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"errors"
+)
+
+var ErrHmacHash = errors.New("HMAC requires an available hash algorithm")
+
+// Hmac is a Signer computing a HMAC with a shared secret.
+type Hmac struct {
+	key []byte
+}
+
+// 生成HMAC对象
+func NewHmac(key []byte) *Hmac {
+	return &Hmac{
+		key: key,
+	}
+}
+
+// 计算HMAC
+func (this *Hmac) Sign(rawData []byte, algorithmSign crypto.Hash) ([]byte, error) {
+	if algorithmSign == 0 || !algorithmSign.Available() {
+		return nil, ErrHmacHash
+	}
+
+	h := hmac.New(algorithmSign.New, this.key)
+	h.Write(rawData)
+	return h.Sum(nil), nil
+}
+
+// 以常量时间比较HMAC
+func (this *Hmac) Verify(rawData []byte, sign []byte, algorithmSign crypto.Hash) bool {
+	expected, err := this.Sign(rawData, algorithmSign)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(expected, sign)
+}
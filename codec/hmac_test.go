@@ -0,0 +1,23 @@
+package codec_test
+
+import (
+	"crypto"
+	"testing"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHmac(t *testing.T) {
+	var signer codec.Signer = codec.NewHmac([]byte("secret"))
+
+	sign, err := signer.Sign([]byte(testBody), crypto.SHA256)
+	assert.Nil(t, err)
+	assert.Equal(t, 32, len(sign))
+	assert.True(t, signer.Verify([]byte(testBody), sign, crypto.SHA256))
+	assert.False(t, signer.Verify([]byte(testBody), sign, crypto.SHA512))
+	assert.False(t, codec.NewHmac([]byte("other")).Verify([]byte(testBody), sign, crypto.SHA256))
+
+	_, err = signer.Sign([]byte(testBody), 0)
+	assert.Equal(t, codec.ErrHmacHash, err)
+}
@@ -0,0 +1,100 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 00:41:26
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 00:41:26
+ * @Description:
+ */
+package jwt
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type (
+	// Claims are the registered claims of RFC 7519, the times are unix seconds.
+	// Embed it in a struct to add private claims.
+	Claims struct {
+		Issuer    string   `json:"iss,omitempty"`
+		Subject   string   `json:"sub,omitempty"`
+		Audience  Audience `json:"aud,omitempty"`
+		ExpiresAt int64    `json:"exp,omitempty"`
+		NotBefore int64    `json:"nbf,omitempty"`
+		IssuedAt  int64    `json:"iat,omitempty"`
+		ID        string   `json:"jti,omitempty"`
+	}
+
+	// Audience is the aud claim, it's a single string or an array of strings.
+	Audience []string
+)
+
+// NewClaims returns the Claims of a token issued now and valid for ttl.
+func NewClaims(issuer, subject string, ttl time.Duration, audience ...string) Claims {
+	now := time.Now()
+	return Claims{
+		Issuer:    issuer,
+		Subject:   subject,
+		Audience:  audience,
+		ExpiresAt: now.Add(ttl).Unix(),
+		IssuedAt:  now.Unix(),
+	}
+}
+
+// Contains returns true if aud is one of the audiences.
+func (a Audience) Contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var multi []string
+	if err := json.Unmarshal(data, &multi); err != nil {
+		return ErrMalformed
+	}
+	*a = multi
+	return nil
+}
+
+// validate checks the times and the issuer and audience expected by v.
+func (c *Claims) validate(v *Verifier) error {
+	now := v.now().Unix()
+	leeway := int64(v.leeway / time.Second)
+
+	if c.ExpiresAt > 0 && now > c.ExpiresAt+leeway {
+		return ErrExpired
+	}
+	if c.NotBefore > 0 && now < c.NotBefore-leeway {
+		return ErrNotValidYet
+	}
+	if c.IssuedAt > 0 && now < c.IssuedAt-leeway {
+		return ErrIssuedInFuture
+	}
+	if v.requireExp && c.ExpiresAt == 0 {
+		return ErrExpired
+	}
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return ErrIssuer
+	}
+	if v.audience != "" && !c.Audience.Contains(v.audience) {
+		return ErrAudience
+	}
+	return nil
+}
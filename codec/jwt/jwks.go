@@ -0,0 +1,194 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 00:41:26
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 00:41:26
+ * @Description:
+ */
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+var ErrJWK = errors.New("jwt: invalid or unsupported JWK")
+
+type (
+	// JWK is a public JSON Web Key of RFC 7517.
+	JWK struct {
+		KeyType   string    `json:"kty"`
+		Use       string    `json:"use,omitempty"`
+		KeyID     string    `json:"kid,omitempty"`
+		Algorithm Algorithm `json:"alg,omitempty"`
+		// RSA
+		N string `json:"n,omitempty"`
+		E string `json:"e,omitempty"`
+		// EC and OKP
+		Curve string `json:"crv,omitempty"`
+		X     string `json:"x,omitempty"`
+		Y     string `json:"y,omitempty"`
+	}
+
+	// JWKS is a JSON Web Key Set.
+	JWKS struct {
+		Keys []JWK `json:"keys"`
+	}
+)
+
+// MarshalJWKS returns the JWKS of the public keys of s, the HMAC keys are secret
+// and left out.
+func (s *KeySet) MarshalJWKS() ([]byte, error) {
+	jwks := JWKS{
+		Keys: make([]JWK, 0),
+	}
+	for _, key := range s.Keys() {
+		if key.public == nil {
+			continue
+		}
+
+		jwk, err := newJWK(key)
+		if err != nil {
+			return nil, err
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return json.Marshal(jwks)
+}
+
+// ParseJWKS returns a KeySet holding the keys of a JWKS, the keys can only verify.
+// The alg of a key is optional, it's inferred from kty and crv if missing, RSA
+// keys are taken as RS256. The keys with an unsupported alg are skipped.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var jwks JWKS
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, ErrJWK
+	}
+
+	s := NewKeySet()
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if jwk.Algorithm == "" {
+			jwk.Algorithm = jwk.inferAlgorithm()
+		}
+		if !jwk.Algorithm.asymmetric() {
+			continue
+		}
+
+		public, err := jwk.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		if !jwk.Algorithm.matches(public) {
+			return nil, ErrJWK
+		}
+
+		pem, err := encodePublicKey(public)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := NewKey(jwk.KeyID, jwk.Algorithm, pem, "")
+		if err != nil {
+			return nil, err
+		}
+		s.Add(key)
+	}
+	return s, nil
+}
+
+func newJWK(key *Key) (JWK, error) {
+	jwk := JWK{
+		Use:       "sig",
+		KeyID:     key.ID,
+		Algorithm: key.Algorithm,
+	}
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encoding.EncodeToString(public.N.Bytes())
+		jwk.E = encoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = public.Curve.Params().Name
+		jwk.X = encoding.EncodeToString(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = encoding.EncodeToString(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encoding.EncodeToString(public)
+	default:
+		return jwk, ErrJWK
+	}
+	return jwk, nil
+}
+
+func (jwk JWK) publicKey() (crypto.PublicKey, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, ErrJWK
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if jwk.Curve != "P-256" {
+			return nil, ErrJWK
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "OKP":
+		x, err := encoding.DecodeString(jwk.X)
+		if jwk.Curve != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, ErrJWK
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, ErrJWK
+	}
+}
+
+// inferAlgorithm returns the algorithm of a key without alg, or "" if its type
+// isn't supported.
+func (jwk JWK) inferAlgorithm() Algorithm {
+	switch {
+	case jwk.KeyType == "RSA":
+		return RS256
+	case jwk.KeyType == "EC" && jwk.Curve == "P-256":
+		return ES256
+	case jwk.KeyType == "OKP" && jwk.Curve == "Ed25519":
+		return EdDSA
+	default:
+		return ""
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := encoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, ErrJWK
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,182 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 00:41:26
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 00:41:26
+ * @Description:
+ */
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed      = errors.New("jwt: malformed token")
+	ErrAlgorithm      = errors.New("jwt: unsupported or unexpected algorithm")
+	ErrKeyNotFound    = errors.New("jwt: key not found")
+	ErrNoPrivateKey   = errors.New("jwt: key can not sign without private key")
+	ErrSignature      = errors.New("jwt: invalid signature")
+	ErrExpired        = errors.New("jwt: token is expired")
+	ErrNotValidYet    = errors.New("jwt: token is not valid yet")
+	ErrIssuedInFuture = errors.New("jwt: token is issued in the future")
+	ErrIssuer         = errors.New("jwt: unexpected issuer")
+	ErrAudience       = errors.New("jwt: unexpected audience")
+)
+
+var encoding = base64.RawURLEncoding
+
+type (
+	// Header is the JOSE header of a token.
+	Header struct {
+		Algorithm Algorithm `json:"alg"`
+		Type      string    `json:"typ,omitempty"`
+		KeyID     string    `json:"kid,omitempty"`
+	}
+
+	// Verifier parses the tokens signed by the keys of a KeySet and validates their claims.
+	Verifier struct {
+		keys       *KeySet
+		issuer     string
+		audience   string
+		leeway     time.Duration
+		requireExp bool
+		now        func() time.Time
+	}
+
+	// VerifierOption customizes a Verifier.
+	VerifierOption func(*Verifier)
+)
+
+// WithIssuer makes the Verifier reject the tokens not issued by issuer.
+func WithIssuer(issuer string) VerifierOption {
+	return func(v *Verifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience makes the Verifier reject the tokens not intended for audience.
+func WithAudience(audience string) VerifierOption {
+	return func(v *Verifier) {
+		v.audience = audience
+	}
+}
+
+// WithLeeway sets the clock skew tolerated on exp, nbf and iat.
+func WithLeeway(leeway time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// WithRequireExpiration makes the Verifier reject the tokens without exp.
+func WithRequireExpiration() VerifierOption {
+	return func(v *Verifier) {
+		v.requireExp = true
+	}
+}
+
+// WithClock sets the function returning the current time, it's meant for tests.
+func WithClock(now func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
+
+// Sign returns the compact JWS of claims signed by key, claims is any value
+// marshaled to a JSON object, usually a struct embedding Claims.
+func Sign(key *Key, claims any) (string, error) {
+	header, err := json.Marshal(Header{
+		Algorithm: key.Algorithm,
+		Type:      "JWT",
+		KeyID:     key.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encoding.EncodeToString(header) + "." + encoding.EncodeToString(payload)
+	sign, err := key.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + encoding.EncodeToString(sign), nil
+}
+
+// NewVerifier returns a Verifier using the keys of keys.
+func NewVerifier(keys *KeySet, opts ...VerifierOption) *Verifier {
+	v := &Verifier{
+		keys: keys,
+		now:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Parse verifies the signature of token with the key named by its kid, validates
+// its registered claims, and unmarshals its payload into claims if not nil.
+// The alg of the token must be the one of the key.
+func (v *Verifier) Parse(token string, claims any) (Header, error) {
+	var header Header
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return header, ErrMalformed
+	}
+
+	rawHeader, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return header, ErrMalformed
+	}
+	if err = json.Unmarshal(rawHeader, &header); err != nil {
+		return header, ErrMalformed
+	}
+
+	key, ok := v.keys.lookup(header.KeyID)
+	if !ok {
+		return header, ErrKeyNotFound
+	}
+	if header.Algorithm != key.Algorithm {
+		return header, ErrAlgorithm
+	}
+
+	sign, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return header, ErrMalformed
+	}
+	if !key.verify([]byte(parts[0]+"."+parts[1]), sign) {
+		return header, ErrSignature
+	}
+
+	payload, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return header, ErrMalformed
+	}
+
+	var registered Claims
+	if err = json.Unmarshal(payload, &registered); err != nil {
+		return header, ErrMalformed
+	}
+	if err = registered.validate(v); err != nil {
+		return header, err
+	}
+
+	if claims != nil {
+		if err = json.Unmarshal(payload, claims); err != nil {
+			return header, ErrMalformed
+		}
+	}
+	return header, nil
+}
@@ -0,0 +1,181 @@
+package jwt_test
+
+import (
+	"crypto/elliptic"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/cnzf1/gocore/codec/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+type userClaims struct {
+	jwt.Claims
+	Role string `json:"role"`
+}
+
+func newKeys(t *testing.T) []*jwt.Key {
+	rsaObj, err := codec.CreateRsaPkcs8(2048)
+	assert.Nil(t, err)
+	ecObj, err := codec.CreateEcdsa(elliptic.P256())
+	assert.Nil(t, err)
+	edObj, err := codec.CreateEd25519()
+	assert.Nil(t, err)
+
+	rs, err := jwt.NewKey("rs", jwt.RS256, rsaObj.PublicKey(), rsaObj.PrivateKey())
+	assert.Nil(t, err)
+	ps, err := jwt.NewKey("ps", jwt.PS256, "", rsaObj.PrivateKey())
+	assert.Nil(t, err)
+	es, err := jwt.NewKey("es", jwt.ES256, "", ecObj.PrivateKey())
+	assert.Nil(t, err)
+	ed, err := jwt.NewKey("ed", jwt.EdDSA, "", edObj.PrivateKey())
+	assert.Nil(t, err)
+
+	return []*jwt.Key{rs, ps, es, ed, jwt.NewHMACKey("hs", []byte("secret"))}
+}
+
+func TestSignAndParse(t *testing.T) {
+	keys := newKeys(t)
+	v := jwt.NewVerifier(jwt.NewKeySet(keys...), jwt.WithIssuer("gocore"), jwt.WithAudience("api"))
+
+	for _, key := range keys {
+		claims := userClaims{
+			Claims: jwt.NewClaims("gocore", "user-1", time.Minute, "api"),
+			Role:   "admin",
+		}
+		token, err := jwt.Sign(key, claims)
+		assert.Nil(t, err)
+
+		var parsed userClaims
+		header, err := v.Parse(token, &parsed)
+		assert.Nil(t, err, key.Algorithm)
+		assert.Equal(t, key.ID, header.KeyID)
+		assert.Equal(t, key.Algorithm, header.Algorithm)
+		assert.Equal(t, claims, parsed)
+
+		parts := strings.Split(token, ".")
+		_, err = v.Parse(parts[0]+"."+parts[1]+".AAAA", nil)
+		assert.Equal(t, jwt.ErrSignature, err, key.Algorithm)
+	}
+}
+
+func TestParseClaims(t *testing.T) {
+	key := jwt.NewHMACKey("hs", []byte("secret"))
+	now := time.Now()
+	clock := func() time.Time { return now }
+	v := jwt.NewVerifier(jwt.NewKeySet(key), jwt.WithLeeway(time.Minute), jwt.WithClock(clock),
+		jwt.WithIssuer("gocore"), jwt.WithAudience("api"))
+
+	cases := []struct {
+		claims jwt.Claims
+		err    error
+	}{
+		{jwt.Claims{Issuer: "gocore", Audience: jwt.Audience{"web", "api"}}, nil},
+		{jwt.Claims{Issuer: "gocore", Audience: jwt.Audience{"api"}, ExpiresAt: now.Add(-30 * time.Second).Unix()}, nil},
+		{jwt.Claims{Issuer: "gocore", Audience: jwt.Audience{"api"}, ExpiresAt: now.Add(-2 * time.Minute).Unix()}, jwt.ErrExpired},
+		{jwt.Claims{Issuer: "gocore", Audience: jwt.Audience{"api"}, NotBefore: now.Add(2 * time.Minute).Unix()}, jwt.ErrNotValidYet},
+		{jwt.Claims{Issuer: "gocore", Audience: jwt.Audience{"api"}, IssuedAt: now.Add(2 * time.Minute).Unix()}, jwt.ErrIssuedInFuture},
+		{jwt.Claims{Issuer: "other", Audience: jwt.Audience{"api"}}, jwt.ErrIssuer},
+		{jwt.Claims{Issuer: "gocore", Audience: jwt.Audience{"web"}}, jwt.ErrAudience},
+	}
+	for _, c := range cases {
+		token, err := jwt.Sign(key, c.claims)
+		assert.Nil(t, err)
+		_, err = v.Parse(token, nil)
+		assert.Equal(t, c.err, err)
+	}
+
+	token, err := jwt.Sign(key, jwt.Claims{Issuer: "gocore", Audience: jwt.Audience{"api"}})
+	assert.Nil(t, err)
+	_, err = jwt.NewVerifier(jwt.NewKeySet(key), jwt.WithRequireExpiration()).Parse(token, nil)
+	assert.Equal(t, jwt.ErrExpired, err)
+}
+
+func TestParseAlgorithmConfusion(t *testing.T) {
+	keys := newKeys(t)
+	rs := keys[0]
+
+	// a token claiming HS256 for a RSA key must not be verified with the public key.
+	hs := jwt.NewHMACKey("rs", []byte("public key"))
+	token, err := jwt.Sign(hs, jwt.Claims{})
+	assert.Nil(t, err)
+	_, err = jwt.NewVerifier(jwt.NewKeySet(rs)).Parse(token, nil)
+	assert.Equal(t, jwt.ErrAlgorithm, err)
+
+	_, err = jwt.NewVerifier(jwt.NewKeySet(keys...)).Parse("eyJhbGciOiJub25lIn0.e30.", nil)
+	assert.Equal(t, jwt.ErrKeyNotFound, err)
+	_, err = jwt.NewVerifier(jwt.NewKeySet(rs)).Parse("eyJhbGciOiJub25lIn0.e30.", nil)
+	assert.Equal(t, jwt.ErrAlgorithm, err)
+	_, err = jwt.NewVerifier(jwt.NewKeySet(rs)).Parse("foo", nil)
+	assert.Equal(t, jwt.ErrMalformed, err)
+}
+
+func TestJWKS(t *testing.T) {
+	keys := newKeys(t)
+	data, err := jwt.NewKeySet(keys...).MarshalJWKS()
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), `"hs"`)
+
+	public, err := jwt.ParseJWKS(data)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(public.Keys()))
+
+	v := jwt.NewVerifier(public)
+	for _, key := range keys[:4] {
+		token, err := jwt.Sign(key, jwt.Claims{Subject: "user-1"})
+		assert.Nil(t, err)
+
+		var claims jwt.Claims
+		_, err = v.Parse(token, &claims)
+		assert.Nil(t, err, key.Algorithm)
+		assert.Equal(t, "user-1", claims.Subject)
+
+		imported, ok := public.Get(key.ID)
+		assert.True(t, ok)
+		_, err = jwt.Sign(imported, jwt.Claims{})
+		assert.Equal(t, jwt.ErrNoPrivateKey, err)
+	}
+
+	_, err = jwt.ParseJWKS([]byte(`{"keys":[{"kty":"EC","alg":"ES256","crv":"P-256","x":"AQ","y":"AQ"}]}`))
+	assert.NotNil(t, err)
+}
+
+func TestJWKSWithoutAlg(t *testing.T) {
+	keys := newKeys(t)
+	data, err := jwt.NewKeySet(keys...).MarshalJWKS()
+	assert.Nil(t, err)
+
+	// alg is optional, many providers leave it out.
+	var jwks jwt.JWKS
+	assert.Nil(t, json.Unmarshal(data, &jwks))
+	for i := range jwks.Keys {
+		jwks.Keys[i].Algorithm = ""
+	}
+	jwks.Keys = append(jwks.Keys, jwt.JWK{KeyType: "EC", Curve: "P-521"})
+	data, err = json.Marshal(jwks)
+	assert.Nil(t, err)
+
+	public, err := jwt.ParseJWKS(data)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(public.Keys()))
+
+	v := jwt.NewVerifier(public)
+	for _, key := range keys[:4] {
+		imported, ok := public.Get(key.ID)
+		assert.True(t, ok)
+		if key.Algorithm == jwt.PS256 {
+			// a RSA key without alg is taken as RS256.
+			assert.Equal(t, jwt.RS256, imported.Algorithm)
+			continue
+		}
+		assert.Equal(t, key.Algorithm, imported.Algorithm)
+
+		token, err := jwt.Sign(key, jwt.Claims{Subject: "user-1"})
+		assert.Nil(t, err)
+		_, err = v.Parse(token, nil)
+		assert.Nil(t, err, key.Algorithm)
+	}
+}
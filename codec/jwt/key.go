@@ -0,0 +1,240 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 00:41:26
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 00:41:26
+ * @Description:
+ */
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"sort"
+	"sync"
+
+	"github.com/cnzf1/gocore/codec"
+)
+
+// The supported signing algorithms, named as in RFC 7518.
+const (
+	RS256 Algorithm = "RS256"
+	PS256 Algorithm = "PS256"
+	ES256 Algorithm = "ES256"
+	EdDSA Algorithm = "EdDSA"
+	HS256 Algorithm = "HS256"
+)
+
+type (
+	// Algorithm is the alg header of a JWS.
+	Algorithm string
+
+	// Key is a signing key identified by its kid.
+	Key struct {
+		ID        string
+		Algorithm Algorithm
+		signer    codec.Signer
+		public    crypto.PublicKey
+		canSign   bool
+	}
+
+	// KeySet holds the keys by kid, it's concurrent safe.
+	KeySet struct {
+		lock sync.RWMutex
+		keys map[string]*Key
+	}
+)
+
+// NewKey returns an asymmetric Key, publicKey is a PEM encoded PKIX public key
+// and privateKey a PEM encoded PKCS#1, SEC1 or PKCS#8 private key. A key without
+// private key can only verify, and the public key is derived from the private
+// key if it's empty.
+func NewKey(id string, alg Algorithm, publicKey, privateKey string) (*Key, error) {
+	var signer interface {
+		codec.Signer
+		PublicKey() string
+	}
+	var err error
+
+	switch alg {
+	case RS256:
+		signer, err = codec.NewRsa(publicKey, privateKey)
+	case PS256:
+		signer, err = codec.NewRsa(publicKey, privateKey, codec.WithPSS(rsa.PSSSaltLengthEqualsHash))
+	case ES256:
+		signer, err = codec.NewEcdsa(publicKey, privateKey, codec.WithEcdsaFormat(codec.EcdsaRaw))
+	case EdDSA:
+		signer, err = codec.NewEd25519(publicKey, privateKey)
+	default:
+		return nil, ErrAlgorithm
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	public, err := parsePublicKey(signer.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	if !alg.matches(public) {
+		return nil, ErrAlgorithm
+	}
+
+	return &Key{
+		ID:        id,
+		Algorithm: alg,
+		signer:    signer,
+		public:    public,
+		canSign:   privateKey != "",
+	}, nil
+}
+
+// NewHMACKey returns a HS256 Key using the shared secret.
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{
+		ID:        id,
+		Algorithm: HS256,
+		signer:    codec.NewHmac(secret),
+		canSign:   true,
+	}
+}
+
+// Public returns the public key, or nil for a HMAC key.
+func (k *Key) Public() crypto.PublicKey {
+	return k.public
+}
+
+func (k *Key) sign(data []byte) ([]byte, error) {
+	if !k.canSign {
+		return nil, ErrNoPrivateKey
+	}
+	return k.signer.Sign(data, k.Algorithm.hash())
+}
+
+func (k *Key) verify(data, sign []byte) bool {
+	return k.signer.Verify(data, sign, k.Algorithm.hash())
+}
+
+// NewKeySet returns a KeySet holding keys.
+func NewKeySet(keys ...*Key) *KeySet {
+	s := &KeySet{
+		keys: make(map[string]*Key, len(keys)),
+	}
+	for _, key := range keys {
+		s.keys[key.ID] = key
+	}
+	return s
+}
+
+// Add adds key, replacing the key with the same kid if any.
+func (s *KeySet) Add(key *Key) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.keys[key.ID] = key
+}
+
+// Remove removes the key of kid.
+func (s *KeySet) Remove(kid string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.keys, kid)
+}
+
+// Get returns the key of kid.
+func (s *KeySet) Get(kid string) (*Key, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// Keys returns the keys sorted by kid.
+func (s *KeySet) Keys() []*Key {
+	s.lock.RLock()
+	keys := make([]*Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	s.lock.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// lookup returns the key of kid, a token without kid is accepted when the
+// set holds a single key.
+func (s *KeySet) lookup(kid string) (*Key, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (a Algorithm) hash() crypto.Hash {
+	switch a {
+	case EdDSA:
+		return 0
+	default:
+		return crypto.SHA256
+	}
+}
+
+func (a Algorithm) asymmetric() bool {
+	switch a {
+	case RS256, PS256, ES256, EdDSA:
+		return true
+	default:
+		return false
+	}
+}
+
+func (a Algorithm) matches(public crypto.PublicKey) bool {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return a == RS256 || a == PS256
+	case *ecdsa.PublicKey:
+		return a == ES256 && key.Curve == elliptic.P256()
+	case ed25519.PublicKey:
+		return a == EdDSA
+	default:
+		return false
+	}
+}
+
+func parsePublicKey(publicKey string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return nil, codec.ErrPubKey
+	}
+
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return public, nil
+}
+
+func encodePublicKey(public crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: der,
+	})), nil
+}
@@ -0,0 +1,310 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 01:16:03
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 01:16:03
+ * @Description:
+ */
+package codec
+
+import (
+	"crypto"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	keyringVersion  = 1
+	maxKeyringKeyID = 255
+)
+
+var (
+	ErrNoActiveKey     = errors.New("keyring has no active key")
+	ErrKeyNotFound     = errors.New("keyring has no key with this id")
+	ErrKeyExpired      = errors.New("keyring key is expired")
+	ErrKeyringFormat   = errors.New("malformed keyring payload")
+	ErrDuplicateKeyID  = errors.New("keyring already has a key with this id")
+	ErrInvalidKeyID    = errors.New("keyring key id must be 1 to 255 bytes long")
+	ErrKeyringNoCrypto = errors.New("keyring key has neither crypter nor signer")
+)
+
+type (
+	// KeyringKey is a versioned key of a Keyring, either Crypter or Signer may be nil.
+	//
+	// The key is used to encrypt and sign from ActivateAt until RetireAt, and to
+	// decrypt and verify until ExpireAt, a zero time means no limit.
+	KeyringKey struct {
+		ID         string
+		Crypter    Crypter
+		Signer     Signer
+		ActivateAt time.Time
+		RetireAt   time.Time
+		ExpireAt   time.Time
+	}
+
+	// Keyring is a Crypter and a Signer holding several keys, it encrypts and
+	// signs with the active key and writes its id ahead of the output, so that
+	// it decrypts and verifies with the key the payload names. It's concurrent safe.
+	//
+	// The active key is the key activated last among the keys neither retired nor
+	// expired, so a new key can be added ahead of its activation time to let all
+	// the readers know it before it's used.
+	Keyring struct {
+		lock sync.RWMutex
+		keys map[string]*keyringEntry
+		seq  int
+		now  func() time.Time
+	}
+
+	keyringEntry struct {
+		KeyringKey
+		seq int
+	}
+
+	// KeyringOption customizes a Keyring.
+	KeyringOption func(*Keyring)
+)
+
+// WithKeyringClock sets the function returning the current time, it's meant for tests.
+func WithKeyringClock(now func() time.Time) KeyringOption {
+	return func(k *Keyring) {
+		k.now = now
+	}
+}
+
+// NewKeyring returns a Keyring holding keys.
+func NewKeyring(keys []KeyringKey, opts ...KeyringOption) (*Keyring, error) {
+	k := &Keyring{
+		keys: make(map[string]*keyringEntry, len(keys)),
+		now:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	for _, key := range keys {
+		if err := k.Add(key); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Add adds key, the key ids are unique.
+func (this *Keyring) Add(key KeyringKey) error {
+	if len(key.ID) == 0 || len(key.ID) > maxKeyringKeyID {
+		return ErrInvalidKeyID
+	}
+	if key.Crypter == nil && key.Signer == nil {
+		return ErrKeyringNoCrypto
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if _, ok := this.keys[key.ID]; ok {
+		return ErrDuplicateKeyID
+	}
+
+	this.seq++
+	this.keys[key.ID] = &keyringEntry{
+		KeyringKey: key,
+		seq:        this.seq,
+	}
+	return nil
+}
+
+// Remove removes the key of id, the payloads it produced can not be read anymore.
+func (this *Keyring) Remove(id string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.keys, id)
+}
+
+// Retire makes the key of id stop encrypting and signing at once.
+func (this *Keyring) Retire(id string) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	e, ok := this.keys[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	e.RetireAt = this.now()
+	return nil
+}
+
+// Keys returns the keys in the order they were added.
+func (this *Keyring) Keys() []KeyringKey {
+	// the keys are copied under the lock, Retire changes them in place.
+	this.lock.RLock()
+	entries := make([]keyringEntry, 0, len(this.keys))
+	for _, e := range this.keys {
+		entries = append(entries, *e)
+	}
+	this.lock.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+
+	keys := make([]KeyringKey, len(entries))
+	for i, e := range entries {
+		keys[i] = e.KeyringKey
+	}
+	return keys
+}
+
+// ActiveCrypter returns the id of the key encrypting now.
+func (this *Keyring) ActiveCrypter() (string, error) {
+	e, err := this.active(func(e *keyringEntry) bool { return e.Crypter != nil })
+	if err != nil {
+		return "", err
+	}
+	return e.ID, nil
+}
+
+// ActiveSigner returns the id of the key signing now.
+func (this *Keyring) ActiveSigner() (string, error) {
+	e, err := this.active(func(e *keyringEntry) bool { return e.Signer != nil })
+	if err != nil {
+		return "", err
+	}
+	return e.ID, nil
+}
+
+// Encrypt encrypts rawData with the active key.
+func (this *Keyring) Encrypt(rawData []byte) ([]byte, error) {
+	e, err := this.active(func(e *keyringEntry) bool { return e.Crypter != nil })
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := e.Crypter.Encrypt(rawData)
+	if err != nil {
+		return nil, err
+	}
+	return appendKeyID(e.ID, enc), nil
+}
+
+// Decrypt decrypts encData with the key it names.
+func (this *Keyring) Decrypt(encData []byte) ([]byte, error) {
+	id, enc, err := ParseKeyID(encData)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := this.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.Crypter == nil {
+		return nil, ErrKeyNotFound
+	}
+	return e.Crypter.Decrypt(enc)
+}
+
+func (this *Keyring) EncryptBase64(rawData []byte) (string, error) {
+	return encryptBase64(this, rawData)
+}
+
+func (this *Keyring) DecryptBase64(encData string) ([]byte, error) {
+	return decryptBase64(this, encData)
+}
+
+// Sign signs rawData with the active key.
+func (this *Keyring) Sign(rawData []byte, algorithmSign crypto.Hash) ([]byte, error) {
+	e, err := this.active(func(e *keyringEntry) bool { return e.Signer != nil })
+	if err != nil {
+		return nil, err
+	}
+
+	sign, err := e.Signer.Sign(rawData, algorithmSign)
+	if err != nil {
+		return nil, err
+	}
+	return appendKeyID(e.ID, sign), nil
+}
+
+// Verify verifies sign with the key it names.
+func (this *Keyring) Verify(rawData []byte, sign []byte, algorithmSign crypto.Hash) bool {
+	id, sign, err := ParseKeyID(sign)
+	if err != nil {
+		return false
+	}
+
+	e, err := this.lookup(id)
+	if err != nil || e.Signer == nil {
+		return false
+	}
+	return e.Signer.Verify(rawData, sign, algorithmSign)
+}
+
+// ParseKeyID returns the key id written ahead of a payload of a Keyring, and the
+// payload of the key.
+func ParseKeyID(data []byte) (string, []byte, error) {
+	if len(data) < 2 || data[0] != keyringVersion {
+		return "", nil, ErrKeyringFormat
+	}
+
+	n := int(data[1])
+	if n == 0 || len(data) < 2+n {
+		return "", nil, ErrKeyringFormat
+	}
+	return string(data[2 : 2+n]), data[2+n:], nil
+}
+
+func (this *Keyring) active(usable func(e *keyringEntry) bool) (*keyringEntry, error) {
+	now := this.now()
+
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	var active *keyringEntry
+	for _, e := range this.keys {
+		if !usable(e) || now.Before(e.ActivateAt) || reached(now, e.RetireAt) || reached(now, e.ExpireAt) {
+			continue
+		}
+
+		if active == nil || e.ActivateAt.After(active.ActivateAt) ||
+			(e.ActivateAt.Equal(active.ActivateAt) && e.seq > active.seq) {
+			active = e
+		}
+	}
+
+	if active == nil {
+		return nil, ErrNoActiveKey
+	}
+	return active, nil
+}
+
+func (this *Keyring) lookup(id string) (*keyringEntry, error) {
+	now := this.now()
+
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	e, ok := this.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if reached(now, e.ExpireAt) {
+		return nil, ErrKeyExpired
+	}
+	return e, nil
+}
+
+func appendKeyID(id string, data []byte) []byte {
+	out := make([]byte, 0, 2+len(id)+len(data))
+	out = append(out, keyringVersion, byte(len(id)))
+	out = append(out, id...)
+	return append(out, data...)
+}
+
+// reached returns true if the limit t is set and reached at now.
+func reached(now, t time.Time) bool {
+	return !t.IsZero() && !now.Before(t)
+}
@@ -0,0 +1,139 @@
+package codec_test
+
+import (
+	"bytes"
+	"crypto"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	v1, err := codec.NewAESGCM(bytes.Repeat([]byte{1}, 32))
+	assert.Nil(t, err)
+	v2, err := codec.NewAESGCM(bytes.Repeat([]byte{2}, 32))
+	assert.Nil(t, err)
+
+	var ring codec.Crypter
+	keyring, err := codec.NewKeyring([]codec.KeyringKey{
+		{ID: "v1", Crypter: v1},
+		{ID: "v2", Crypter: v2, ActivateAt: now.Add(time.Hour)},
+	}, codec.WithKeyringClock(clock))
+	assert.Nil(t, err)
+	ring = keyring
+
+	enc1, err := ring.Encrypt([]byte(testBody))
+	assert.Nil(t, err)
+	id, _, err := codec.ParseKeyID(enc1)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", id)
+
+	// v2 becomes active, the payloads of v1 are still readable.
+	now = now.Add(2 * time.Hour)
+	active, err := keyring.ActiveCrypter()
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", active)
+
+	enc2, err := ring.EncryptBase64([]byte(testBody))
+	assert.Nil(t, err)
+	dec, err := ring.DecryptBase64(enc2)
+	assert.Nil(t, err)
+	assert.Equal(t, testBody, string(dec))
+	dec, err = ring.Decrypt(enc1)
+	assert.Nil(t, err)
+	assert.Equal(t, testBody, string(dec))
+
+	assert.Nil(t, keyring.Retire("v2"))
+	active, err = keyring.ActiveCrypter()
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", active)
+
+	keyring.Remove("v1")
+	_, err = ring.Decrypt(enc1)
+	assert.Equal(t, codec.ErrKeyNotFound, err)
+	_, err = ring.Encrypt([]byte(testBody))
+	assert.Equal(t, codec.ErrNoActiveKey, err)
+	assert.Equal(t, codec.ErrDuplicateKeyID, keyring.Add(codec.KeyringKey{ID: "v2", Crypter: v1}))
+}
+
+func TestKeyringKeysRetire(t *testing.T) {
+	c, err := codec.NewAESGCM(bytes.Repeat([]byte{1}, 32))
+	assert.Nil(t, err)
+	keyring, err := codec.NewKeyring([]codec.KeyringKey{{ID: "v1", Crypter: c}, {ID: "v2", Crypter: c}})
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			keyring.Retire("v1")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		keys := keyring.Keys()
+		assert.Equal(t, "v1", keys[0].ID)
+	}
+	wg.Wait()
+	assert.False(t, keyring.Keys()[0].RetireAt.IsZero())
+}
+
+func TestKeyringExpire(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	v1, err := codec.NewAESGCM(bytes.Repeat([]byte{1}, 16))
+	assert.Nil(t, err)
+	keyring, err := codec.NewKeyring([]codec.KeyringKey{
+		{ID: "v1", Crypter: v1, RetireAt: now.Add(time.Hour), ExpireAt: now.Add(2 * time.Hour)},
+	}, codec.WithKeyringClock(clock))
+	assert.Nil(t, err)
+
+	enc, err := keyring.Encrypt([]byte(testBody))
+	assert.Nil(t, err)
+
+	now = now.Add(90 * time.Minute)
+	_, err = keyring.Encrypt([]byte(testBody))
+	assert.Equal(t, codec.ErrNoActiveKey, err)
+	_, err = keyring.Decrypt(enc)
+	assert.Nil(t, err)
+
+	now = now.Add(time.Hour)
+	_, err = keyring.Decrypt(enc)
+	assert.Equal(t, codec.ErrKeyExpired, err)
+}
+
+func TestKeyringSign(t *testing.T) {
+	ed, err := codec.CreateEd25519()
+	assert.Nil(t, err)
+	rsaObj, err := codec.NewRsa(pubKey, priKey)
+	assert.Nil(t, err)
+
+	var signer codec.Signer
+	keyring, err := codec.NewKeyring([]codec.KeyringKey{{ID: "rsa", Signer: rsaObj}})
+	assert.Nil(t, err)
+	signer = keyring
+
+	sign1, err := signer.Sign([]byte(testBody), crypto.SHA256)
+	assert.Nil(t, err)
+	assert.True(t, signer.Verify([]byte(testBody), sign1, crypto.SHA256))
+
+	assert.Nil(t, keyring.Add(codec.KeyringKey{ID: "ed", Signer: ed, ActivateAt: time.Now()}))
+	sign2, err := signer.Sign([]byte(testBody), 0)
+	assert.Nil(t, err)
+	id, _, err := codec.ParseKeyID(sign2)
+	assert.Nil(t, err)
+	assert.Equal(t, "ed", id)
+	assert.True(t, signer.Verify([]byte(testBody), sign2, 0))
+	assert.True(t, signer.Verify([]byte(testBody), sign1, crypto.SHA256))
+	assert.False(t, signer.Verify([]byte("other"), sign1, crypto.SHA256))
+
+	_, err = keyring.Encrypt([]byte(testBody))
+	assert.Equal(t, codec.ErrNoActiveKey, err)
+}
@@ -0,0 +1,288 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 03:05:26
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 03:05:26
+ * @Description:
+ */
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"strings"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeGob     = "application/x-gob"
+	ContentTypeMsgPack = "application/msgpack"
+)
+
+// The compressions negotiated by a content type suffix, such as application/json+gzip.
+const (
+	CompressGzip  Compression = "gzip"
+	CompressFlate Compression = "deflate"
+)
+
+// DefaultMaxDecompressedSize is the largest decompressed data of a compressed
+// Marshaler by default.
+const DefaultMaxDecompressedSize = 32 << 20
+
+var (
+	ErrContentType = errors.New("unknown or malformed content type")
+	ErrCompression = errors.New("unknown compression")
+	ErrTooLarge    = errors.New("decompressed data is too large")
+)
+
+var (
+	JSON    Marshaler = JSONMarshaler{}
+	Gob     Marshaler = GobMarshaler{}
+	MsgPack Marshaler = MsgPackMarshaler{}
+)
+
+type (
+	// Marshaler encodes and decodes values in the format of its content type.
+	Marshaler interface {
+		ContentType() string
+		Marshal(v any) ([]byte, error)
+		Unmarshal(data []byte, v any) error
+	}
+
+	// Compression is a compression algorithm of a compressed Marshaler.
+	Compression string
+
+	// JSONMarshaler is a Marshaler using encoding/json.
+	JSONMarshaler struct{}
+
+	// GobMarshaler is a Marshaler using encoding/gob, the values in interfaces
+	// must be registered with gob.Register.
+	GobMarshaler struct{}
+
+	// MsgPackMarshaler is a Marshaler using MessagePack, the struct fields are
+	// renamed by the msgpack tag.
+	MsgPackMarshaler struct{}
+
+	compressMarshaler struct {
+		Marshaler
+		compression Compression
+		maxSize     int64
+	}
+
+	// CompressOption customizes a compressed Marshaler.
+	CompressOption func(*compressMarshaler)
+)
+
+var registry = struct {
+	lock       sync.RWMutex
+	marshalers map[string]Marshaler
+}{
+	marshalers: map[string]Marshaler{
+		ContentTypeJSON:         JSON,
+		ContentTypeGob:          Gob,
+		ContentTypeMsgPack:      MsgPack,
+		"application/x-msgpack": MsgPack,
+	},
+}
+
+// RegisterMarshaler registers m for its content type and aliases, replacing the
+// Marshaler registered before. It's concurrent safe.
+func RegisterMarshaler(m Marshaler, aliases ...string) error {
+	contentTypes := make([]string, 0, len(aliases)+1)
+	for _, contentType := range append([]string{m.ContentType()}, aliases...) {
+		mediaType, err := parseContentType(contentType)
+		if err != nil {
+			return err
+		}
+		contentTypes = append(contentTypes, mediaType)
+	}
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	for _, contentType := range contentTypes {
+		registry.marshalers[contentType] = m
+	}
+	return nil
+}
+
+// GetMarshaler returns the Marshaler of contentType, its parameters such as charset
+// are ignored. A content type suffixed by +gzip or +deflate, not registered
+// itself, gets the Marshaler of the content type without suffix, compressed
+// with opts.
+func GetMarshaler(contentType string, opts ...CompressOption) (Marshaler, error) {
+	mediaType, err := parseContentType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	if m, ok := registry.marshalers[mediaType]; ok {
+		return m, nil
+	}
+
+	for _, compression := range []Compression{CompressGzip, CompressFlate} {
+		base := strings.TrimSuffix(mediaType, "+"+string(compression))
+		if base == mediaType {
+			continue
+		}
+		if m, ok := registry.marshalers[base]; ok {
+			return Compress(m, compression, opts...)
+		}
+	}
+	return nil, ErrContentType
+}
+
+// Marshal encodes v by the Marshaler of contentType.
+func Marshal(contentType string, v any) ([]byte, error) {
+	m, err := GetMarshaler(contentType)
+	if err != nil {
+		return nil, err
+	}
+	return m.Marshal(v)
+}
+
+// Unmarshal decodes data into v by the Marshaler of contentType.
+func Unmarshal(contentType string, data []byte, v any) error {
+	m, err := GetMarshaler(contentType)
+	if err != nil {
+		return err
+	}
+	return m.Unmarshal(data, v)
+}
+
+// WithMaxDecompressedSize sets the largest decompressed data, Unmarshal fails
+// with ErrTooLarge beyond it. It's DefaultMaxDecompressedSize by default.
+func WithMaxDecompressedSize(size int64) CompressOption {
+	return func(m *compressMarshaler) {
+		m.maxSize = size
+	}
+}
+
+// Compress returns a Marshaler compressing the output of m, its content type is
+// the one of m suffixed by +gzip or +deflate.
+func Compress(m Marshaler, compression Compression, opts ...CompressOption) (Marshaler, error) {
+	if compression != CompressGzip && compression != CompressFlate {
+		return nil, ErrCompression
+	}
+
+	cm := &compressMarshaler{
+		Marshaler:   m,
+		compression: compression,
+		maxSize:     DefaultMaxDecompressedSize,
+	}
+	for _, opt := range opts {
+		opt(cm)
+	}
+	return cm, nil
+}
+
+func (JSONMarshaler) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (JSONMarshaler) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONMarshaler) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (GobMarshaler) ContentType() string {
+	return ContentTypeGob
+}
+
+func (GobMarshaler) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobMarshaler) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (MsgPackMarshaler) ContentType() string {
+	return ContentTypeMsgPack
+}
+
+func (MsgPackMarshaler) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgPackMarshaler) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+func (this *compressMarshaler) ContentType() string {
+	return this.Marshaler.ContentType() + "+" + string(this.compression)
+}
+
+func (this *compressMarshaler) Marshal(v any) ([]byte, error) {
+	data, err := this.Marshaler.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	if this.compression == CompressGzip {
+		w = gzip.NewWriter(&buf)
+	} else {
+		// only fails on an invalid level.
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (this *compressMarshaler) Unmarshal(data []byte, v any) error {
+	var r io.ReadCloser
+	if this.compression == CompressGzip {
+		var err error
+		if r, err = gzip.NewReader(bytes.NewReader(data)); err != nil {
+			return err
+		}
+	} else {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer r.Close()
+
+	// one more byte is read to tell the data exceeding the max size.
+	data, err := ioutil.ReadAll(io.LimitReader(r, this.maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > this.maxSize {
+		return ErrTooLarge
+	}
+	return this.Marshaler.Unmarshal(data, v)
+}
+
+func parseContentType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", ErrContentType
+	}
+	return mediaType, nil
+}
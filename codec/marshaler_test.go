@@ -0,0 +1,123 @@
+package codec_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+type marshalerItem struct {
+	Name  string
+	Count int
+	Tags  []string
+}
+
+func TestMarshalers(t *testing.T) {
+	item := marshalerItem{Name: "item", Count: 3, Tags: []string{"a", "b"}}
+	contentTypes := []string{
+		"application/json; charset=utf-8",
+		"application/x-gob",
+		"application/msgpack",
+		"application/x-msgpack",
+		"application/json+gzip",
+		"application/msgpack+deflate",
+	}
+	for _, contentType := range contentTypes {
+		data, err := codec.Marshal(contentType, item)
+		assert.Nil(t, err, contentType)
+
+		var decoded marshalerItem
+		assert.Nil(t, codec.Unmarshal(contentType, data, &decoded), contentType)
+		assert.Equal(t, item, decoded, contentType)
+	}
+
+	m, err := codec.GetMarshaler("Application/JSON+gzip")
+	assert.Nil(t, err)
+	assert.Equal(t, "application/json+gzip", m.ContentType())
+
+	_, err = codec.GetMarshaler("application/xml")
+	assert.Equal(t, codec.ErrContentType, err)
+	_, err = codec.GetMarshaler("application/xml+gzip")
+	assert.Equal(t, codec.ErrContentType, err)
+	_, err = codec.GetMarshaler(";")
+	assert.Equal(t, codec.ErrContentType, err)
+	_, err = codec.Compress(codec.JSON, "br")
+	assert.Equal(t, codec.ErrCompression, err)
+}
+
+func TestCompressMarshaler(t *testing.T) {
+	item := marshalerItem{Name: strings.Repeat("item", 1000)}
+	plain, err := codec.JSON.Marshal(item)
+	assert.Nil(t, err)
+
+	for _, compression := range []codec.Compression{codec.CompressGzip, codec.CompressFlate} {
+		m, err := codec.Compress(codec.JSON, compression)
+		assert.Nil(t, err)
+
+		data, err := m.Marshal(item)
+		assert.Nil(t, err)
+		assert.Less(t, len(data), len(plain)/10)
+
+		var decoded marshalerItem
+		assert.Nil(t, m.Unmarshal(data, &decoded))
+		assert.Equal(t, item, decoded)
+		assert.NotNil(t, m.Unmarshal(plain, &decoded))
+	}
+}
+
+func TestCompressMarshalerMaxSize(t *testing.T) {
+	item := marshalerItem{Name: strings.Repeat("item", 1000)}
+	for _, compression := range []codec.Compression{codec.CompressGzip, codec.CompressFlate} {
+		m, err := codec.Compress(codec.JSON, compression, codec.WithMaxDecompressedSize(1000))
+		assert.Nil(t, err)
+
+		data, err := m.Marshal(item)
+		assert.Nil(t, err)
+		var decoded marshalerItem
+		assert.Equal(t, codec.ErrTooLarge, m.Unmarshal(data, &decoded))
+
+		small := marshalerItem{Name: "item"}
+		data, err = m.Marshal(small)
+		assert.Nil(t, err)
+		assert.Nil(t, m.Unmarshal(data, &decoded))
+		assert.Equal(t, small, decoded)
+	}
+
+	m, err := codec.GetMarshaler("application/json+gzip", codec.WithMaxDecompressedSize(1000))
+	assert.Nil(t, err)
+	data, err := m.Marshal(item)
+	assert.Nil(t, err)
+	var decoded marshalerItem
+	assert.Equal(t, codec.ErrTooLarge, m.Unmarshal(data, &decoded))
+}
+
+type upperMarshaler struct{}
+
+func (upperMarshaler) ContentType() string {
+	return "text/x-upper"
+}
+
+func (upperMarshaler) Marshal(v any) ([]byte, error) {
+	return bytes.ToUpper([]byte(v.(string))), nil
+}
+
+func (upperMarshaler) Unmarshal(data []byte, v any) error {
+	*v.(*string) = string(bytes.ToLower(data))
+	return nil
+}
+
+func TestRegisterMarshaler(t *testing.T) {
+	assert.Nil(t, codec.RegisterMarshaler(upperMarshaler{}, "text/x-shout"))
+
+	data, err := codec.Marshal("text/x-shout+gzip", "hello")
+	assert.Nil(t, err)
+
+	var s string
+	assert.Nil(t, codec.Unmarshal("text/x-upper+gzip", data, &s))
+	assert.Equal(t, "hello", s)
+
+	assert.Equal(t, codec.ErrContentType, codec.RegisterMarshaler(upperMarshaler{}, ""))
+}
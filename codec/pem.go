@@ -0,0 +1,81 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 23:21:37
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 23:21:37
+ * @Description:
+ */
+package codec
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+
+	"github.com/cnzf1/gocore/lang"
+)
+
+const (
+	pemPrivateKey          = "PRIVATE KEY"
+	pemRSAPrivateKey       = "RSA PRIVATE KEY"
+	pemECPrivateKey        = "EC PRIVATE KEY"
+	pemEncryptedPrivateKey = "ENCRYPTED PRIVATE KEY"
+	pemPublicKey           = "PUBLIC KEY"
+	pemCertificate         = "CERTIFICATE"
+)
+
+var ErrPEM = errors.New("failed to decode PEM block")
+
+// 解析pkcs1, pkcs8或sec1格式的私钥
+func parsePrivateKeyPEM(key string) (lang.AnyType, error) {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return nil, ErrPEM
+	}
+
+	switch block.Type {
+	case pemRSAPrivateKey:
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case pemECPrivateKey:
+		return x509.ParseECPrivateKey(block.Bytes)
+	case pemPrivateKey:
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, ErrPrivKey
+	}
+}
+
+// 解析pkix格式的公钥
+func parsePublicKeyPEM(key string) (lang.AnyType, error) {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return nil, ErrPEM
+	}
+	if block.Type != pemPublicKey {
+		return nil, ErrPubKey
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// 编码pkcs8格式私钥和pkix格式公钥
+func encodeKeyPairPEM(privateKey, publicKey lang.AnyType) (string, string, error) {
+	derPkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	derPkix, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	return encodePEM(pemPrivateKey, derPkcs8), encodePEM(pemPublicKey, derPkix), nil
+}
+
+func encodePEM(typ string, der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  typ,
+		Bytes: der,
+	}))
+}
@@ -0,0 +1,224 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 02:03:17
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 02:03:17
+ * @Description:
+ */
+package codec
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/youmark/pkcs8"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+var (
+	ErrPassphrase      = errors.New("incorrect passphrase or unsupported key encryption")
+	ErrNotEncrypted    = errors.New("private key is not encrypted")
+	ErrPkcs12NoKey     = errors.New("PKCS#12 bundle has no private key")
+	ErrPkcs12NoCert    = errors.New("PKCS#12 bundle has no certificate")
+	ErrCertificatePEM  = errors.New("failed to parse PEM block containing the certificate")
+	ErrKeyCertMismatch = errors.New("private key does not match the certificate")
+)
+
+// Pkcs12 is the content of a PKCS#12 bundle: a private key, its certificate and
+// the chain of CA certificates.
+type Pkcs12 struct {
+	PrivateKey  crypto.PrivateKey
+	Certificate *x509.Certificate
+	CACerts     []*x509.Certificate
+}
+
+// 使用口令加密pkcs1, pkcs8或sec1格式私钥, 生成PBES2(PBKDF2-SHA256, AES-256-CBC)加密的pkcs8格式私钥
+func EncryptPrivateKeyPEM(privateKey string, passphrase []byte) (string, error) {
+	if len(passphrase) == 0 {
+		return "", ErrPassphrase
+	}
+
+	key, err := parsePrivateKeyPEM(privateKey)
+	if err != nil {
+		return "", ErrPrivKey
+	}
+
+	der, err := pkcs8.MarshalPrivateKey(key, passphrase, nil)
+	if err != nil {
+		return "", err
+	}
+	return encodePEM(pemEncryptedPrivateKey, der), nil
+}
+
+// 解密加密的pkcs8格式私钥, 生成未加密的pkcs8格式私钥, 可直接用于NewRsa, NewEcdsa和NewEd25519
+func DecryptPrivateKeyPEM(privateKey string, passphrase []byte) (string, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return "", ErrPEM
+	}
+	if block.Type != pemEncryptedPrivateKey {
+		return "", ErrNotEncrypted
+	}
+
+	key, _, err := pkcs8.ParsePrivateKey(block.Bytes, passphrase)
+	if err != nil {
+		return "", ErrPassphrase
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return encodePEM(pemPrivateKey, der), nil
+}
+
+// 解析PKCS#12文件内容
+func DecodePkcs12(data []byte, password string) (*Pkcs12, error) {
+	key, cert, caCerts, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pkcs12{
+		PrivateKey:  key,
+		Certificate: cert,
+		CACerts:     caCerts,
+	}, nil
+}
+
+// 读取并解析PKCS#12文件
+func LoadPkcs12File(filename, password string) (*Pkcs12, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodePkcs12(data, password)
+}
+
+// 由PEM格式的私钥和证书链生成Pkcs12, 第一个证书为私钥的证书, 私钥可以是加密的pkcs8格式
+func NewPkcs12(privateKey, certificates string, passphrase []byte) (*Pkcs12, error) {
+	var err error
+	if strings.Contains(privateKey, "BEGIN "+pemEncryptedPrivateKey) {
+		if privateKey, err = DecryptPrivateKeyPEM(privateKey, passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := parsePrivateKeyPEM(privateKey)
+	if err != nil {
+		return nil, ErrPrivKey
+	}
+
+	certs, err := ParseCertificatesPEM(certificates)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, ErrPkcs12NoCert
+	}
+
+	p := &Pkcs12{
+		PrivateKey:  key,
+		Certificate: certs[0],
+		CACerts:     certs[1:],
+	}
+	if _, err = p.TLSCertificate(); err != nil {
+		return nil, ErrKeyCertMismatch
+	}
+	return p, nil
+}
+
+// 编码为PKCS#12文件内容, 使用AES-256-CBC和PBKDF2-SHA256加密
+func (this *Pkcs12) Encode(password string) ([]byte, error) {
+	if this.PrivateKey == nil {
+		return nil, ErrPkcs12NoKey
+	}
+	if this.Certificate == nil {
+		return nil, ErrPkcs12NoCert
+	}
+
+	return pkcs12.Modern.Encode(this.PrivateKey, this.Certificate, this.CACerts, password)
+}
+
+// 未加密的pkcs8格式私钥, 可直接用于NewRsa, NewEcdsa和NewEd25519
+func (this *Pkcs12) PrivateKeyPEM() (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(this.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	return encodePEM(pemPrivateKey, der), nil
+}
+
+// 使用口令加密的pkcs8格式私钥
+func (this *Pkcs12) EncryptedPrivateKeyPEM(passphrase []byte) (string, error) {
+	privateKey, err := this.PrivateKeyPEM()
+	if err != nil {
+		return "", err
+	}
+	return EncryptPrivateKeyPEM(privateKey, passphrase)
+}
+
+// pkix格式公钥
+func (this *Pkcs12) PublicKeyPEM() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(this.Certificate.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return encodePEM(pemPublicKey, der), nil
+}
+
+// PEM格式证书链, 私钥的证书在前
+func (this *Pkcs12) CertificatesPEM() string {
+	var b strings.Builder
+	for _, cert := range this.chain() {
+		b.WriteString(encodePEM(pemCertificate, cert.Raw))
+	}
+	return b.String()
+}
+
+// 生成TLS证书, 可直接用于tls.Config
+func (this *Pkcs12) TLSCertificate() (tls.Certificate, error) {
+	keyPEM, err := this.PrivateKeyPEM()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.X509KeyPair([]byte(this.CertificatesPEM()), []byte(keyPEM))
+}
+
+func (this *Pkcs12) chain() []*x509.Certificate {
+	certs := make([]*x509.Certificate, 0, len(this.CACerts)+1)
+	if this.Certificate != nil {
+		certs = append(certs, this.Certificate)
+	}
+	return append(certs, this.CACerts...)
+}
+
+// 解析PEM格式的证书, 忽略其他类型的块
+func ParseCertificatesPEM(certificates string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(certificates)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != pemCertificate {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, ErrCertificatePEM
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
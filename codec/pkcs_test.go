@@ -0,0 +1,140 @@
+package codec_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCertificate(t *testing.T, cn string, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return cert
+}
+
+func TestEncryptPrivateKeyPEM(t *testing.T) {
+	rsaObj, err := codec.CreateRsa(1024)
+	assert.Nil(t, err)
+	passphrase := []byte("passphrase")
+
+	encrypted, err := codec.EncryptPrivateKeyPEM(rsaObj.PrivateKey(), passphrase)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(encrypted, "BEGIN ENCRYPTED PRIVATE KEY"))
+
+	_, err = codec.DecryptPrivateKeyPEM(encrypted, []byte("wrong"))
+	assert.Equal(t, codec.ErrPassphrase, err)
+	_, err = codec.DecryptPrivateKeyPEM(rsaObj.PrivateKey(), passphrase)
+	assert.Equal(t, codec.ErrNotEncrypted, err)
+
+	decrypted, err := codec.DecryptPrivateKeyPEM(encrypted, passphrase)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(decrypted, "BEGIN PRIVATE KEY"))
+
+	// the encrypted key is fed straight into NewRsa.
+	_, err = codec.NewRsa("", encrypted)
+	assert.Equal(t, codec.ErrPassphrase, err)
+	loaded, err := codec.NewRsa("", encrypted, codec.WithPassphrase(passphrase))
+	assert.Nil(t, err)
+
+	sign, err := loaded.Sign([]byte(testBody), crypto.SHA256)
+	assert.Nil(t, err)
+	assert.True(t, rsaObj.Verify([]byte(testBody), sign, crypto.SHA256))
+
+	ecObj, err := codec.CreateEcdsa(elliptic.P256())
+	assert.Nil(t, err)
+	encrypted, err = codec.EncryptPrivateKeyPEM(ecObj.PrivateKey(), passphrase)
+	assert.Nil(t, err)
+	_, err = codec.NewEcdsa("", encrypted, codec.WithEcdsaPassphrase(passphrase))
+	assert.Nil(t, err)
+
+	edObj, err := codec.CreateEd25519()
+	assert.Nil(t, err)
+	encrypted, err = codec.EncryptPrivateKeyPEM(edObj.PrivateKey(), passphrase)
+	assert.Nil(t, err)
+	_, err = codec.NewEd25519("", encrypted)
+	assert.Equal(t, codec.ErrPassphrase, err)
+	edLoaded, err := codec.NewEd25519("", encrypted, codec.WithEd25519Passphrase(passphrase))
+	assert.Nil(t, err)
+	sign, err = edLoaded.Sign([]byte(testBody), 0)
+	assert.Nil(t, err)
+	assert.True(t, edObj.Verify([]byte(testBody), sign, 0))
+}
+
+func TestPkcs12(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	ca := newCertificate(t, "ca", caKey, nil, nil)
+
+	rsaObj, err := codec.CreateRsaPkcs8(2048)
+	assert.Nil(t, err)
+	block, _ := pem.Decode([]byte(rsaObj.PrivateKey()))
+	leafKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	assert.Nil(t, err)
+	leaf := newCertificate(t, "leaf", leafKey.(crypto.Signer), ca, caKey)
+
+	chain := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})) +
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}))
+	encrypted, err := codec.EncryptPrivateKeyPEM(rsaObj.PrivateKey(), []byte("passphrase"))
+	assert.Nil(t, err)
+
+	p, err := codec.NewPkcs12(encrypted, chain, []byte("passphrase"))
+	assert.Nil(t, err)
+	data, err := p.Encode("password")
+	assert.Nil(t, err)
+
+	_, err = codec.DecodePkcs12(data, "wrong")
+	assert.NotNil(t, err)
+	decoded, err := codec.DecodePkcs12(data, "password")
+	assert.Nil(t, err)
+	assert.Equal(t, leaf.Raw, decoded.Certificate.Raw)
+	assert.Equal(t, 1, len(decoded.CACerts))
+	assert.Equal(t, ca.Raw, decoded.CACerts[0].Raw)
+	assert.Equal(t, chain, decoded.CertificatesPEM())
+
+	tlsCert, err := decoded.TLSCertificate()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(tlsCert.Certificate))
+
+	privateKey, err := decoded.PrivateKeyPEM()
+	assert.Nil(t, err)
+	publicKey, err := decoded.PublicKeyPEM()
+	assert.Nil(t, err)
+	loaded, err := codec.NewRsa(publicKey, privateKey)
+	assert.Nil(t, err)
+	sign, err := loaded.Sign([]byte(testBody), crypto.SHA256)
+	assert.Nil(t, err)
+	assert.True(t, rsaObj.Verify([]byte(testBody), sign, crypto.SHA256))
+
+	// the key must match the first certificate.
+	other, err := codec.CreateRsa(1024)
+	assert.Nil(t, err)
+	_, err = codec.NewPkcs12(other.PrivateKey(), chain, nil)
+	assert.Equal(t, codec.ErrKeyCertMismatch, err)
+	_, err = codec.NewPkcs12(rsaObj.PrivateKey(), "", nil)
+	assert.Equal(t, codec.ErrPkcs12NoCert, err)
+}
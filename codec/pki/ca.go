@@ -0,0 +1,307 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 02:31:52
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 02:31:52
+ * @Description:
+ */
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/cnzf1/gocore/codec"
+)
+
+var (
+	ErrKeyType    = errors.New("pki: unsupported key type")
+	ErrNotCA      = errors.New("pki: certificate is not a CA")
+	ErrNotSigner  = errors.New("pki: private key can not sign")
+	ErrCommonName = errors.New("pki: common name is empty")
+	ErrValidity   = errors.New("pki: validity starts after the CA expires")
+)
+
+var serialLimit = new(big.Int).Lsh(big.NewInt(1), 128)
+
+// CA issues certificates signed by its key, it's either a self-signed root or
+// an intermediate issued by another CA.
+type CA struct {
+	Certificate
+	root *x509.Certificate
+}
+
+// NewCA returns a self-signed root CA named commonName.
+func NewCA(commonName string, opts ...Option) (*CA, error) {
+	o := newOptions(defaultCAValidity, x509.KeyUsageCertSign|x509.KeyUsageCRLSign, nil, opts)
+	key, err := generateKey(o.keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := newTemplate(commonName, o)
+	if err != nil {
+		return nil, err
+	}
+	setCA(tmpl, o)
+
+	cert, err := createCertificate(tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{
+		Certificate: Certificate{
+			Cert:       cert,
+			PrivateKey: key,
+		},
+		root: cert,
+	}, nil
+}
+
+// LoadCA returns the CA of certPEM and keyPEM, certPEM is the certificate of the
+// CA followed by its chain, and keyPEM is the private key encrypted with
+// passphrase or not.
+func LoadCA(certPEM, keyPEM string, passphrase []byte) (*CA, error) {
+	p, err := codec.NewPkcs12(keyPEM, certPEM, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Certificate.IsCA {
+		return nil, ErrNotCA
+	}
+
+	key, ok := p.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, ErrNotSigner
+	}
+
+	ca := &CA{
+		Certificate: Certificate{
+			Cert:       p.Certificate,
+			PrivateKey: key,
+			Chain:      p.CACerts,
+		},
+	}
+
+	// the root is the top of the chain if it's given.
+	top := ca.Cert
+	if len(ca.Chain) > 0 {
+		top = ca.Chain[len(ca.Chain)-1]
+	}
+	if isSelfSigned(top) {
+		ca.root = top
+		if top != ca.Cert {
+			ca.Chain = ca.Chain[:len(ca.Chain)-1]
+		}
+	}
+	return ca, nil
+}
+
+// LoadCAFiles returns the CA of the PEM files certFile and keyFile.
+func LoadCAFiles(certFile, keyFile string, passphrase []byte) (*CA, error) {
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return LoadCA(string(certPEM), string(keyPEM), passphrase)
+}
+
+// Issue returns a leaf certificate named commonName, its key usage is digital
+// signature and its extended key usages must be set with WithExtKeyUsage.
+func (ca *CA) Issue(commonName string, opts ...Option) (*Certificate, error) {
+	return ca.issue(commonName, x509.KeyUsageDigitalSignature, nil, opts)
+}
+
+// IssueServer returns a server certificate named commonName, the hosts it serves
+// are set with WithHosts.
+func (ca *CA) IssueServer(commonName string, opts ...Option) (*Certificate, error) {
+	return ca.issue(commonName, x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, opts)
+}
+
+// IssueClient returns a client certificate named commonName.
+func (ca *CA) IssueClient(commonName string, opts ...Option) (*Certificate, error) {
+	return ca.issue(commonName, x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, opts)
+}
+
+// IssueCA returns an intermediate CA named commonName.
+func (ca *CA) IssueCA(commonName string, opts ...Option) (*CA, error) {
+	o := newOptions(defaultCAValidity, x509.KeyUsageCertSign|x509.KeyUsageCRLSign, nil, opts)
+	tmpl, err := newTemplate(commonName, o)
+	if err != nil {
+		return nil, err
+	}
+	setCA(tmpl, o)
+
+	c, err := ca.sign(tmpl, o)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{
+		Certificate: *c,
+		root:        ca.root,
+	}, nil
+}
+
+// CertPool returns the pool trusting the root of the CA, or the CA itself if
+// its root is unknown.
+func (ca *CA) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	if ca.root != nil {
+		pool.AddCert(ca.root)
+	} else {
+		pool.AddCert(ca.Cert)
+	}
+	return pool
+}
+
+// ServerTLSConfig returns the tls.Config of a server presenting server, and
+// requiring the client certificates issued under the CA.
+func (ca *CA) ServerTLSConfig(server *Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{server.TLSCertificate()},
+		ClientCAs:    ca.CertPool(),
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// ClientTLSConfig returns the tls.Config of a client trusting the server
+// certificates issued under the CA, and presenting client if not nil.
+// serverName is verified against the server certificate if not empty,
+// otherwise the host dialed is.
+func (ca *CA) ClientTLSConfig(client *Certificate, serverName string) *tls.Config {
+	config := &tls.Config{
+		RootCAs:    ca.CertPool(),
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if client != nil {
+		config.Certificates = []tls.Certificate{client.TLSCertificate()}
+	}
+	return config
+}
+
+func (ca *CA) issue(commonName string, keyUsage x509.KeyUsage, extKeyUsage []x509.ExtKeyUsage, opts []Option) (*Certificate, error) {
+	o := newOptions(defaultLeafValidity, keyUsage, extKeyUsage, opts)
+	tmpl, err := newTemplate(commonName, o)
+	if err != nil {
+		return nil, err
+	}
+	return ca.sign(tmpl, o)
+}
+
+func (ca *CA) sign(tmpl *x509.Certificate, o *options) (*Certificate, error) {
+	// a certificate outliving its CA is cut to the validity of the CA.
+	if !tmpl.NotBefore.Before(ca.Cert.NotAfter) {
+		return nil, ErrValidity
+	}
+	if tmpl.NotBefore.Before(ca.Cert.NotBefore) {
+		tmpl.NotBefore = ca.Cert.NotBefore
+	}
+	if tmpl.NotAfter.After(ca.Cert.NotAfter) {
+		tmpl.NotAfter = ca.Cert.NotAfter
+	}
+
+	key, err := generateKey(o.keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := createCertificate(tmpl, ca.Cert, key.Public(), ca.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := ca.Chain
+	if ca.Cert != ca.root {
+		chain = append([]*x509.Certificate{ca.Cert}, ca.Chain...)
+	}
+	return &Certificate{
+		Cert:       cert,
+		PrivateKey: key,
+		Chain:      chain,
+	}, nil
+}
+
+func newTemplate(commonName string, o *options) (*x509.Certificate, error) {
+	if commonName == "" {
+		return nil, ErrCommonName
+	}
+
+	serial, err := rand.Int(rand.Reader, serialLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := o.subject
+	subject.CommonName = commonName
+	return &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             o.notBefore,
+		NotAfter:              o.notAfter,
+		KeyUsage:              o.keyUsage,
+		ExtKeyUsage:           o.extKeyUsage,
+		BasicConstraintsValid: true,
+		DNSNames:              o.dnsNames,
+		IPAddresses:           o.ips,
+		EmailAddresses:        o.emails,
+		URIs:                  o.uris,
+	}, nil
+}
+
+func setCA(tmpl *x509.Certificate, o *options) {
+	tmpl.IsCA = true
+	if o.maxPathLen >= 0 {
+		tmpl.MaxPathLen = o.maxPathLen
+		tmpl.MaxPathLenZero = o.maxPathLen == 0
+	}
+}
+
+func createCertificate(tmpl, parent *x509.Certificate, pub crypto.PublicKey, priv crypto.Signer) (*x509.Certificate, error) {
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, priv)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+func generateKey(keyType KeyType) (crypto.Signer, error) {
+	switch keyType {
+	case ECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case ECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case RSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case RSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case Ed25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, ErrKeyType
+	}
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
@@ -0,0 +1,98 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 02:31:52
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 02:31:52
+ * @Description:
+ */
+package pki
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/cnzf1/gocore/filex"
+)
+
+// Certificate is a certificate, its private key, and the chain of its issuers
+// up to the root CA excluded.
+type Certificate struct {
+	Cert       *x509.Certificate
+	PrivateKey crypto.Signer
+	Chain      []*x509.Certificate
+}
+
+// CertPEM returns the PEM of the certificate followed by its chain.
+func (c *Certificate) CertPEM() string {
+	var b strings.Builder
+	b.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Cert.Raw}))
+	for _, cert := range c.Chain {
+		b.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	}
+	return b.String()
+}
+
+// KeyPEM returns the PKCS#8 PEM of the private key, it's encrypted with
+// passphrase if not empty.
+func (c *Certificate) KeyPEM(passphrase []byte) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(c.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	key := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if len(passphrase) == 0 {
+		return key, nil
+	}
+	return codec.EncryptPrivateKeyPEM(key, passphrase)
+}
+
+// WriteFiles writes CertPEM to certFile and KeyPEM to keyFile, the key file is
+// only readable by its owner. The missing directories are created.
+func (c *Certificate) WriteFiles(certFile, keyFile string, passphrase []byte) error {
+	key, err := c.KeyPEM(passphrase)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range []string{certFile, keyFile} {
+		if err = filex.MkdirIfNotExist(filepath.Dir(file)); err != nil {
+			return err
+		}
+	}
+
+	if err = ioutil.WriteFile(certFile, []byte(c.CertPEM()), 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(keyFile, []byte(key), 0600)
+}
+
+// Pkcs12 returns the certificate, its chain and private key as a codec.Pkcs12.
+func (c *Certificate) Pkcs12() *codec.Pkcs12 {
+	return &codec.Pkcs12{
+		PrivateKey:  c.PrivateKey,
+		Certificate: c.Cert,
+		CACerts:     c.Chain,
+	}
+}
+
+// TLSCertificate returns the certificate to set in tls.Config.
+func (c *Certificate) TLSCertificate() tls.Certificate {
+	chain := make([][]byte, 0, len(c.Chain)+1)
+	chain = append(chain, c.Cert.Raw)
+	for _, cert := range c.Chain {
+		chain = append(chain, cert.Raw)
+	}
+
+	return tls.Certificate{
+		Certificate: chain,
+		PrivateKey:  c.PrivateKey,
+		Leaf:        c.Cert,
+	}
+}
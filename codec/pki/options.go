@@ -0,0 +1,149 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 02:31:52
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 02:31:52
+ * @Description:
+ */
+package pki
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The key types of the generated certificates.
+const (
+	ECDSAP256 KeyType = iota
+	ECDSAP384
+	RSA2048
+	RSA4096
+	Ed25519
+)
+
+const (
+	defaultCAValidity   = 10 * 365 * 24 * time.Hour
+	defaultLeafValidity = 365 * 24 * time.Hour
+	// the clock skew tolerated on NotBefore.
+	backdate = 5 * time.Minute
+)
+
+type (
+	// KeyType is the type of the private key of a certificate.
+	KeyType int
+
+	// Option customizes a certificate to create.
+	Option func(*options)
+
+	options struct {
+		keyType     KeyType
+		subject     pkix.Name
+		notBefore   time.Time
+		notAfter    time.Time
+		validFor    time.Duration
+		dnsNames    []string
+		ips         []net.IP
+		emails      []string
+		uris        []*url.URL
+		keyUsage    x509.KeyUsage
+		extKeyUsage []x509.ExtKeyUsage
+		maxPathLen  int
+	}
+)
+
+// WithKeyType sets the key type, ECDSA P-256 by default.
+func WithKeyType(keyType KeyType) Option {
+	return func(o *options) {
+		o.keyType = keyType
+	}
+}
+
+// WithSubject sets the subject, its CommonName is overwritten by the name given
+// to NewCA or Issue.
+func WithSubject(subject pkix.Name) Option {
+	return func(o *options) {
+		o.subject = subject
+	}
+}
+
+// WithValidity sets the validity window, it wins over WithValidFor.
+func WithValidity(notBefore, notAfter time.Time) Option {
+	return func(o *options) {
+		o.notBefore = notBefore
+		o.notAfter = notAfter
+	}
+}
+
+// WithValidFor sets the validity from now on, 10 years for a CA and 1 year for
+// a leaf by default. NotBefore is backdated a few minutes to tolerate clock skew.
+func WithValidFor(d time.Duration) Option {
+	return func(o *options) {
+		o.validFor = d
+	}
+}
+
+// WithHosts adds the subject alternative names, hosts are IP addresses, email
+// addresses, URIs or DNS names.
+func WithHosts(hosts ...string) Option {
+	return func(o *options) {
+		for _, h := range hosts {
+			if ip := net.ParseIP(h); ip != nil {
+				o.ips = append(o.ips, ip)
+			} else if strings.Contains(h, "://") {
+				if u, err := url.Parse(h); err == nil {
+					o.uris = append(o.uris, u)
+				}
+			} else if strings.Contains(h, "@") {
+				o.emails = append(o.emails, h)
+			} else {
+				o.dnsNames = append(o.dnsNames, h)
+			}
+		}
+	}
+}
+
+// WithKeyUsage sets the key usage, replacing the default of the certificate kind.
+func WithKeyUsage(usage x509.KeyUsage) Option {
+	return func(o *options) {
+		o.keyUsage = usage
+	}
+}
+
+// WithExtKeyUsage sets the extended key usages, replacing the default of the
+// certificate kind.
+func WithExtKeyUsage(usages ...x509.ExtKeyUsage) Option {
+	return func(o *options) {
+		o.extKeyUsage = usages
+	}
+}
+
+// WithMaxPathLen limits the number of intermediate CAs below a CA, 0 means
+// it can only issue leaves, and -1 means no limit, the default.
+func WithMaxPathLen(n int) Option {
+	return func(o *options) {
+		o.maxPathLen = n
+	}
+}
+
+func newOptions(validFor time.Duration, keyUsage x509.KeyUsage, extKeyUsage []x509.ExtKeyUsage, opts []Option) *options {
+	o := &options{
+		validFor:    validFor,
+		keyUsage:    keyUsage,
+		extKeyUsage: extKeyUsage,
+		maxPathLen:  -1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.notBefore.IsZero() && o.notAfter.IsZero() {
+		now := time.Now()
+		o.notBefore = now.Add(-backdate)
+		o.notAfter = now.Add(o.validFor)
+	}
+	return o
+}
@@ -0,0 +1,155 @@
+package pki_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/cnzf1/gocore/codec/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+func handshake(server, client *tls.Config) (serverErr, clientErr error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err, err
+	}
+	defer ln.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+
+		s := tls.Server(conn, server)
+		err = s.Handshake()
+		if err == nil {
+			// the client certificate is verified in TLS 1.3 after the client is done.
+			_, err = s.Write([]byte("ok"))
+		}
+		done <- err
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), client)
+	if err == nil {
+		_, err = conn.Read(make([]byte, 2))
+		conn.Close()
+	}
+	return <-done, err
+}
+
+func TestMutualTLS(t *testing.T) {
+	for _, keyType := range []pki.KeyType{pki.ECDSAP256, pki.RSA2048, pki.Ed25519} {
+		root, err := pki.NewCA("root", pki.WithKeyType(keyType))
+		assert.Nil(t, err)
+		ca, err := root.IssueCA("intermediate", pki.WithMaxPathLen(0), pki.WithKeyType(keyType))
+		assert.Nil(t, err)
+
+		server, err := ca.IssueServer("server", pki.WithHosts("localhost", "127.0.0.1"), pki.WithKeyType(keyType))
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"localhost"}, server.Cert.DNSNames)
+		assert.Equal(t, 1, len(server.Cert.IPAddresses))
+		assert.Equal(t, []*x509.Certificate{ca.Cert}, server.Chain)
+
+		client, err := ca.IssueClient("client", pki.WithKeyType(keyType))
+		assert.Nil(t, err)
+
+		serverErr, clientErr := handshake(ca.ServerTLSConfig(server), root.ClientTLSConfig(client, "localhost"))
+		assert.Nil(t, serverErr, keyType)
+		assert.Nil(t, clientErr, keyType)
+
+		// the server requires a client certificate.
+		serverErr, _ = handshake(ca.ServerTLSConfig(server), ca.ClientTLSConfig(nil, "localhost"))
+		assert.NotNil(t, serverErr)
+
+		// the server certificate is not valid for another name.
+		_, clientErr = handshake(ca.ServerTLSConfig(server), ca.ClientTLSConfig(client, "example.com"))
+		assert.NotNil(t, clientErr)
+
+		// a client certificate can not authenticate a server.
+		_, clientErr = handshake(ca.ServerTLSConfig(client), ca.ClientTLSConfig(client, "client"))
+		assert.NotNil(t, clientErr)
+	}
+}
+
+func TestValidity(t *testing.T) {
+	now := time.Now()
+	ca, err := pki.NewCA("root", pki.WithValidFor(time.Hour))
+	assert.Nil(t, err)
+
+	leaf, err := ca.Issue("leaf", pki.WithValidity(now, now.Add(48*time.Hour)))
+	assert.Nil(t, err)
+	assert.Equal(t, ca.Cert.NotAfter, leaf.Cert.NotAfter)
+
+	_, err = ca.Issue("leaf", pki.WithValidity(now.Add(2*time.Hour), now.Add(3*time.Hour)))
+	assert.Equal(t, pki.ErrValidity, err)
+	_, err = ca.Issue("")
+	assert.Equal(t, pki.ErrCommonName, err)
+	_, err = pki.NewCA("root", pki.WithKeyType(pki.KeyType(-1)))
+	assert.Equal(t, pki.ErrKeyType, err)
+}
+
+func TestWriteAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	root, err := pki.NewCA("root")
+	assert.Nil(t, err)
+	ca, err := root.IssueCA("intermediate")
+	assert.Nil(t, err)
+
+	certFile := filepath.Join(dir, "ca", "ca.pem")
+	keyFile := filepath.Join(dir, "ca", "ca-key.pem")
+	assert.Nil(t, ca.WriteFiles(certFile, keyFile, []byte("passphrase")))
+
+	_, err = pki.LoadCAFiles(certFile, keyFile, []byte("wrong"))
+	assert.Equal(t, codec.ErrPassphrase, err)
+	loaded, err := pki.LoadCAFiles(certFile, keyFile, []byte("passphrase"))
+	assert.Nil(t, err)
+
+	server, err := loaded.IssueServer("server", pki.WithHosts("localhost"))
+	assert.Nil(t, err)
+	assert.Equal(t, ca.Cert.Raw, server.Chain[0].Raw)
+
+	// the root is unknown, so both sides trust the intermediate itself.
+	client, err := loaded.IssueClient("client")
+	assert.Nil(t, err)
+	serverErr, clientErr := handshake(loaded.ServerTLSConfig(server), loaded.ClientTLSConfig(client, "localhost"))
+	assert.Nil(t, serverErr)
+	assert.Nil(t, clientErr)
+
+	// the root completes the chain of the loaded CA.
+	rootPEM := root.CertPEM()
+	keyPEM, err := ca.KeyPEM(nil)
+	assert.Nil(t, err)
+	loaded, err = pki.LoadCA(ca.CertPEM()+rootPEM, keyPEM, nil)
+	assert.Nil(t, err)
+	client, err = loaded.IssueClient("client")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(client.Chain))
+	serverErr, clientErr = handshake(loaded.ServerTLSConfig(server), root.ClientTLSConfig(client, "localhost"))
+	assert.Nil(t, serverErr)
+	assert.Nil(t, clientErr)
+
+	_, err = pki.LoadCA(server.CertPEM(), mustKeyPEM(t, server), nil)
+	assert.Equal(t, pki.ErrNotCA, err)
+
+	// the bundle is readable as PKCS#12.
+	data, err := client.Pkcs12().Encode("password")
+	assert.Nil(t, err)
+	p, err := codec.DecodePkcs12(data, "password")
+	assert.Nil(t, err)
+	assert.Equal(t, client.Cert.Raw, p.Certificate.Raw)
+}
+
+func mustKeyPEM(t *testing.T, c *pki.Certificate) string {
+	key, err := c.KeyPEM(nil)
+	assert.Nil(t, err)
+	return key
+}
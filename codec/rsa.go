@@ -2,7 +2,7 @@
 * @Author: cnzf1
 * @Date: 2021-08-05 17:31:50
  * @LastEditors: cnzf1
- * @LastEditTime: 2023-03-01 16:49:29
+ * @LastEditTime: 2026-10-17 23:05:41
 * @Description:
 */
 package codec
@@ -28,28 +28,92 @@ var (
 	ErrPubKey        = errors.New("failed to parse PEM block containing the public key")
 	ErrPrivKeyNotRsa = errors.New("private key type is not RSA")
 	ErrPubKeyNotRsa  = errors.New("public key type is not RSA")
+	ErrNoPubKey      = errors.New("public key is missing")
+	ErrNoPrivKey     = errors.New("private key is missing")
+	ErrKeyTooSmall   = errors.New("RSA key is too small for the padding scheme")
+	ErrPSSHash       = errors.New("PSS requires a hash algorithm")
+	ErrOAEPHash      = errors.New("OAEP requires an available hash algorithm")
 )
 
-type Rsa struct {
-	privateKey    string
-	publicKey     string
-	rsaPrivateKey *rsa.PrivateKey
-	rsaPublicKey  *rsa.PublicKey
+// 加密填充方式
+const (
+	EncPKCS1v15 RsaEncPadding = iota
+	EncOAEP
+)
+
+// 签名方式
+const (
+	SignPKCS1v15 RsaSignScheme = iota
+	SignPSS
+)
+
+type (
+	RsaEncPadding int
+	RsaSignScheme int
+
+	Rsa struct {
+		privateKey    string
+		publicKey     string
+		rsaPrivateKey *rsa.PrivateKey
+		rsaPublicKey  *rsa.PublicKey
+		encPadding    RsaEncPadding
+		oaepHash      crypto.Hash
+		oaepLabel     []byte
+		signScheme    RsaSignScheme
+		pssSaltLength int
+		passphrase    []byte
+	}
+
+	RsaOption func(*Rsa)
+)
+
+// 设置加密的pkcs8格式私钥的口令
+func WithPassphrase(passphrase []byte) RsaOption {
+	return func(r *Rsa) {
+		r.passphrase = passphrase
+	}
 }
 
-// 生成RSA对象
-func NewRsa(publicKey, privateKey string) (*Rsa, error) {
+// 使用OAEP加密填充, hash为0时使用SHA256, hash未链接时NewRsa返回ErrOAEPHash
+func WithOAEP(hash crypto.Hash, label []byte) RsaOption {
+	return func(r *Rsa) {
+		if hash == 0 {
+			hash = crypto.SHA256
+		}
+		r.encPadding = EncOAEP
+		r.oaepHash = hash
+		r.oaepLabel = label
+	}
+}
+
+// 使用PSS签名, saltLength为rsa.PSSSaltLengthAuto或rsa.PSSSaltLengthEqualsHash或具体长度
+func WithPSS(saltLength int) RsaOption {
+	return func(r *Rsa) {
+		r.signScheme = SignPSS
+		r.pssSaltLength = saltLength
+	}
+}
+
+// 生成RSA对象, 默认使用PKCS1v15加密填充和签名
+func NewRsa(publicKey, privateKey string, opts ...RsaOption) (*Rsa, error) {
 	rsaObj := &Rsa{
 		privateKey: privateKey,
 		publicKey:  publicKey,
 	}
+	for _, opt := range opts {
+		opt(rsaObj)
+	}
+
+	if rsaObj.encPadding == EncOAEP && !rsaObj.oaepHash.Available() {
+		return rsaObj, ErrOAEPHash
+	}
 
 	err := rsaObj.init()
 	return rsaObj, err
 }
 
 // 生成pkcs1格式公钥私钥
-func CreateRsa(keyLength int) (*Rsa, error) {
+func CreateRsa(keyLength int, opts ...RsaOption) (*Rsa, error) {
 	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, keyLength)
 	if err != nil {
 		return nil, err
@@ -70,11 +134,11 @@ func CreateRsa(keyLength int) (*Rsa, error) {
 		Bytes: derPkix,
 	}))
 
-	return NewRsa(publicKey, privateKey)
+	return NewRsa(publicKey, privateKey, opts...)
 }
 
 // 生成pkcs8格式公钥私钥
-func CreateRsaPkcs8(keyLength int) (*Rsa, error) {
+func CreateRsaPkcs8(keyLength int, opts ...RsaOption) (*Rsa, error) {
 	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, keyLength)
 	if err != nil {
 		return nil, err
@@ -95,7 +159,7 @@ func CreateRsaPkcs8(keyLength int) (*Rsa, error) {
 		Bytes: derPkix,
 	}))
 
-	return NewRsa(publicKey, privateKey)
+	return NewRsa(publicKey, privateKey, opts...)
 }
 
 func (this *Rsa) init() (err error) {
@@ -103,13 +167,21 @@ func (this *Rsa) init() (err error) {
 	var publicKey lang.AnyType
 
 	if this.privateKey != "" {
-		block, _ := pem.Decode([]byte(this.privateKey))
+		pemKey := this.privateKey
+		//加密的pkcs8
+		if strings.Contains(pemKey, "BEGIN "+pemEncryptedPrivateKey) {
+			if pemKey, err = DecryptPrivateKeyPEM(pemKey, this.passphrase); err != nil {
+				return err
+			}
+		}
+
+		block, _ := pem.Decode([]byte(pemKey))
 		if block == nil || strings.Index(block.Type, "PRIVATE KEY") < 0 {
 			return ErrPrivKeyNotRsa
 		}
 
 		//pkcs1
-		if strings.Index(this.privateKey, "BEGIN RSA") > 0 {
+		if strings.Index(pemKey, "BEGIN RSA") > 0 {
 			this.rsaPrivateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
 			if err != nil {
 				return ErrPrivKey
@@ -119,7 +191,10 @@ func (this *Rsa) init() (err error) {
 			if err != nil {
 				return ErrPrivKey
 			}
-			this.rsaPrivateKey = privateKey.(*rsa.PrivateKey)
+			var ok bool
+			if this.rsaPrivateKey, ok = privateKey.(*rsa.PrivateKey); !ok {
+				return ErrPrivKeyNotRsa
+			}
 		}
 	}
 
@@ -138,31 +213,47 @@ func (this *Rsa) init() (err error) {
 		}
 		this.rsaPublicKey = publicKey.(*rsa.PublicKey)
 	}
+
+	if this.rsaPublicKey == nil && this.rsaPrivateKey != nil {
+		this.rsaPublicKey = &this.rsaPrivateKey.PublicKey
+		der, err := x509.MarshalPKIXPublicKey(this.rsaPublicKey)
+		if err != nil {
+			return err
+		}
+		this.publicKey = encodePEM(pemPublicKey, der)
+	}
 	return nil
 }
 
-// 公钥加密
+// PEM格式私钥
+func (this *Rsa) PrivateKey() string {
+	return this.privateKey
+}
+
+// PEM格式公钥
+func (this *Rsa) PublicKey() string {
+	return this.publicKey
+}
+
+// 公钥加密, 超过单块长度的数据分块加密
 func (this *Rsa) Encrypt(rawData []byte) ([]byte, error) {
-	blockLength := this.rsaPublicKey.N.BitLen()/8 - 11
-	if len(rawData) <= blockLength {
-		return rsa.EncryptPKCS1v15(rand.Reader, this.rsaPublicKey, []byte(rawData))
+	if this.rsaPublicKey == nil {
+		return nil, ErrNoPubKey
 	}
 
-	buffer := bytes.NewBufferString("")
-
-	pages := len(rawData) / blockLength
+	blockLength := this.maxPlainLength()
+	if blockLength <= 0 {
+		return nil, ErrKeyTooSmall
+	}
 
-	for index := 0; index <= pages; index++ {
-		start := index * blockLength
-		end := (index + 1) * blockLength
-		if index == pages {
-			if start == len(rawData) {
-				continue
-			}
+	buffer := bytes.NewBuffer(make([]byte, 0, (len(rawData)/blockLength+1)*this.rsaPublicKey.Size()))
+	for start := 0; start == 0 || start < len(rawData); start += blockLength {
+		end := start + blockLength
+		if end > len(rawData) {
 			end = len(rawData)
 		}
 
-		chunk, err := rsa.EncryptPKCS1v15(rand.Reader, this.rsaPublicKey, rawData[start:end])
+		chunk, err := this.encryptBlock(rawData[start:end])
 		if err != nil {
 			return nil, err
 		}
@@ -173,25 +264,19 @@ func (this *Rsa) Encrypt(rawData []byte) ([]byte, error) {
 
 // 私钥解密
 func (this *Rsa) Decrypt(encData []byte) ([]byte, error) {
-	blockLength := this.rsaPublicKey.N.BitLen() / 8
-	if len(encData) <= blockLength {
-		return rsa.DecryptPKCS1v15(rand.Reader, this.rsaPrivateKey, encData)
+	if this.rsaPrivateKey == nil {
+		return nil, ErrNoPrivKey
 	}
 
-	buffer := bytes.NewBufferString("")
-
-	pages := len(encData) / blockLength
-	for index := 0; index <= pages; index++ {
-		start := index * blockLength
-		end := (index + 1) * blockLength
-		if index == pages {
-			if start == len(encData) {
-				continue
-			}
+	blockLength := this.rsaPrivateKey.Size()
+	buffer := bytes.NewBuffer(make([]byte, 0, len(encData)))
+	for start := 0; start < len(encData); start += blockLength {
+		end := start + blockLength
+		if end > len(encData) {
 			end = len(encData)
 		}
 
-		chunk, err := rsa.DecryptPKCS1v15(rand.Reader, this.rsaPrivateKey, encData[start:end])
+		chunk, err := this.decryptBlock(encData[start:end])
 		if err != nil {
 			return nil, err
 		}
@@ -200,6 +285,29 @@ func (this *Rsa) Decrypt(encData []byte) ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// 单块明文的最大长度
+func (this *Rsa) maxPlainLength() int {
+	k := this.rsaPublicKey.Size()
+	if this.encPadding == EncOAEP {
+		return k - 2*this.oaepHash.Size() - 2
+	}
+	return k - 11
+}
+
+func (this *Rsa) encryptBlock(data []byte) ([]byte, error) {
+	if this.encPadding == EncOAEP {
+		return rsa.EncryptOAEP(this.oaepHash.New(), rand.Reader, this.rsaPublicKey, data, this.oaepLabel)
+	}
+	return rsa.EncryptPKCS1v15(rand.Reader, this.rsaPublicKey, data)
+}
+
+func (this *Rsa) decryptBlock(data []byte) ([]byte, error) {
+	if this.encPadding == EncOAEP {
+		return rsa.DecryptOAEP(this.oaepHash.New(), rand.Reader, this.rsaPrivateKey, data, this.oaepLabel)
+	}
+	return rsa.DecryptPKCS1v15(rand.Reader, this.rsaPrivateKey, data)
+}
+
 // 公钥加密+base64编码
 func (this *Rsa) EncryptBase64(rawData []byte) (string, error) {
 	enc, err := this.Encrypt(rawData)
@@ -220,9 +328,12 @@ func (this *Rsa) DecryptBase64(encData string) ([]byte, error) {
 	return this.Decrypt(dec)
 }
 
-// 私钥加密
+// 私钥加密, 始终使用PKCS1v15填充
 func (this *Rsa) EncryptEx(rawData []byte) ([]byte, error) {
-	return this.Sign(rawData, crypto.Hash(0))
+	if this.rsaPrivateKey == nil {
+		return nil, ErrNoPrivKey
+	}
+	return rsa.SignPKCS1v15(rand.Reader, this.rsaPrivateKey, crypto.Hash(0), rawData)
 }
 
 // 公钥解密
@@ -263,8 +374,12 @@ func (this *Rsa) DecryptBase64Ex(encData string) ([]byte, error) {
 	return this.DecryptEx(dec)
 }
 
-// 私钥签名
+// 私钥签名, PSS签名必须指定hash算法
 func (this *Rsa) Sign(rawData []byte, algorithmSign crypto.Hash) ([]byte, error) {
+	if this.rsaPrivateKey == nil {
+		return nil, ErrNoPrivKey
+	}
+
 	data := rawData
 	if algorithmSign > 0 {
 		hash := algorithmSign.New()
@@ -272,20 +387,36 @@ func (this *Rsa) Sign(rawData []byte, algorithmSign crypto.Hash) ([]byte, error)
 		data = hash.Sum(nil)
 	}
 
-	sign, err := rsa.SignPKCS1v15(rand.Reader, this.rsaPrivateKey, algorithmSign, data)
-	if err != nil {
-		return nil, err
+	if this.signScheme == SignPSS {
+		if algorithmSign == 0 {
+			return nil, ErrPSSHash
+		}
+		return rsa.SignPSS(rand.Reader, this.rsaPrivateKey, algorithmSign, data, this.pssOptions(algorithmSign))
 	}
-	return sign, err
+	return rsa.SignPKCS1v15(rand.Reader, this.rsaPrivateKey, algorithmSign, data)
 }
 
 // 公钥验签
 func (this *Rsa) Verify(rawData []byte, sign []byte, algorithmSign crypto.Hash) bool {
+	if this.rsaPublicKey == nil || !algorithmSign.Available() {
+		return false
+	}
+
 	h := algorithmSign.New()
 	h.Write(rawData)
+	if this.signScheme == SignPSS {
+		return rsa.VerifyPSS(this.rsaPublicKey, algorithmSign, h.Sum(nil), sign, this.pssOptions(algorithmSign)) == nil
+	}
 	return rsa.VerifyPKCS1v15(this.rsaPublicKey, algorithmSign, h.Sum(nil), sign) == nil
 }
 
+func (this *Rsa) pssOptions(hash crypto.Hash) *rsa.PSSOptions {
+	return &rsa.PSSOptions{
+		SaltLength: this.pssSaltLength,
+		Hash:       hash,
+	}
+}
+
 func MarshalPKCS8PrivateKey(key *rsa.PrivateKey) []byte {
 	info := struct {
 		Version             int
@@ -2,13 +2,14 @@
 * @Author: cnzf1
 * @Date: 2021-08-05 17:31:50
  * @LastEditors: cnzf1
- * @LastEditTime: 2023-03-01 16:50:56
+ * @LastEditTime: 2026-10-17 23:05:41
 * @Description:
 */
 package codec_test
 
 import (
 	"crypto"
+	"crypto/rsa"
 	"encoding/hex"
 	"fmt"
 	"strings"
@@ -166,3 +167,55 @@ func TestCreateRsaSign(t *testing.T) {
 		verify,
 	)
 }
+
+func TestRsaOAEP(t *testing.T) {
+	rsaObj, err := codec.NewRsa(pubKey, priKey, codec.WithOAEP(crypto.SHA256, []byte("label")))
+	assert.Nil(t, err)
+
+	// 2048 bits key with SHA256 holds 190 bytes per block.
+	for n, blocks := range map[int]int{0: 1, 190: 1, 191: 2, 1000: 6} {
+		content := strings.Repeat("H", n)
+		enc, err := rsaObj.Encrypt([]byte(content))
+		assert.Nil(t, err)
+		assert.Equal(t, blocks*256, len(enc))
+
+		dec, err := rsaObj.Decrypt(enc)
+		assert.Nil(t, err)
+		assert.Equal(t, content, string(dec))
+	}
+
+	enc, err := rsaObj.Encrypt([]byte(testBody))
+	assert.Nil(t, err)
+	other, err := codec.NewRsa(pubKey, priKey, codec.WithOAEP(crypto.SHA256, []byte("other")))
+	assert.Nil(t, err)
+	_, err = other.Decrypt(enc)
+	assert.NotNil(t, err)
+	pkcs1, err := codec.NewRsa(pubKey, priKey)
+	assert.Nil(t, err)
+	_, err = pkcs1.Decrypt(enc)
+	assert.NotNil(t, err)
+
+	// MD4 isn't linked in.
+	_, err = codec.NewRsa(pubKey, priKey, codec.WithOAEP(crypto.MD4, nil))
+	assert.Equal(t, codec.ErrOAEPHash, err)
+}
+
+func TestRsaPSS(t *testing.T) {
+	rsaObj, err := codec.CreateRsaPkcs8(2048, codec.WithPSS(rsa.PSSSaltLengthEqualsHash))
+	assert.Nil(t, err)
+
+	sign, err := rsaObj.Sign([]byte(testBody), crypto.SHA256)
+	assert.Nil(t, err)
+	assert.True(t, rsaObj.Verify([]byte(testBody), sign, crypto.SHA256))
+	assert.False(t, rsaObj.Verify([]byte("other"), sign, crypto.SHA256))
+
+	_, err = rsaObj.Sign([]byte(testBody), crypto.Hash(0))
+	assert.Equal(t, codec.ErrPSSHash, err)
+
+	// the raw private key encryption keeps the PKCS1v15 padding.
+	enc, err := rsaObj.EncryptEx([]byte(testBody))
+	assert.Nil(t, err)
+	dec, err := rsaObj.DecryptEx(enc)
+	assert.Nil(t, err)
+	assert.Equal(t, testBody, string(dec))
+}
@@ -0,0 +1,335 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 00:12:50
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 00:12:50
+ * @Description:
+ */
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	// DefaultSegmentSize is the default size of the plaintext sealed in each segment.
+	DefaultSegmentSize = 64 << 10
+	// MaxSegmentSize is the largest segment size a stream can be written or read with.
+	MaxSegmentSize = 16 << 20
+
+	streamVersion     = 1
+	streamKeySize     = 32
+	streamPrefixSize  = 7
+	streamTagSize     = 16
+	streamMagic       = "GCST"
+	streamFixedHeader = len(streamMagic) + 1 + 4 + 2
+)
+
+var (
+	ErrStreamFormat    = errors.New("malformed encrypted stream")
+	ErrStreamTruncated = errors.New("encrypted stream is truncated")
+	ErrStreamClosed    = errors.New("encrypted stream is closed")
+	ErrStreamTooLong   = errors.New("encrypted stream is too long")
+	ErrSegmentSize     = errors.New("invalid segment size")
+)
+
+type (
+	// StreamOption customizes an encrypt writer.
+	StreamOption func(*streamConfig)
+
+	streamConfig struct {
+		segmentSize int
+	}
+
+	// encryptWriter seals the plaintext in segments of segmentSize bytes.
+	//
+	// The stream starts with a header made of a magic, the version, the segment
+	// size, the stream key wrapped by the Encrypter and a random nonce prefix.
+	// Every segment is sealed with AES-256-GCM, authenticating the header, with
+	// a nonce made of the prefix, the segment counter and a flag set on the last
+	// segment only, so that reordered, dropped or truncated segments are detected.
+	encryptWriter struct {
+		w       io.Writer
+		aead    cipher.AEAD
+		header  []byte
+		prefix  []byte
+		buf     []byte
+		out     []byte
+		counter uint32
+		err     error
+	}
+
+	decryptReader struct {
+		r       *bufio.Reader
+		aead    cipher.AEAD
+		header  []byte
+		prefix  []byte
+		segment []byte
+		buf     []byte
+		plain   []byte
+		counter uint32
+		done    bool
+		err     error
+	}
+)
+
+// WithSegmentSize sets the size of the plaintext sealed in each segment, DefaultSegmentSize by default.
+func WithSegmentSize(size int) StreamOption {
+	return func(c *streamConfig) {
+		c.segmentSize = size
+	}
+}
+
+// NewEncryptWriter returns a writer encrypting the data written to it into w,
+// the stream key is wrapped by crypter. Close must be called to seal the last
+// segment, it does not close w.
+func NewEncryptWriter(w io.Writer, crypter Encrypter, opts ...StreamOption) (io.WriteCloser, error) {
+	cfg := &streamConfig{
+		segmentSize: DefaultSegmentSize,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.segmentSize <= 0 || cfg.segmentSize > MaxSegmentSize {
+		return nil, ErrSegmentSize
+	}
+
+	key := make([]byte, streamKeySize)
+	prefix := make([]byte, streamPrefixSize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, err
+	}
+
+	wrapped, err := crypter.Encrypt(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) > math.MaxUint16 {
+		return nil, ErrStreamFormat
+	}
+
+	aead, err := newStreamAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := bytes.NewBuffer(make([]byte, 0, streamFixedHeader+len(wrapped)+streamPrefixSize))
+	header.WriteString(streamMagic)
+	header.WriteByte(streamVersion)
+	binary.Write(header, binary.BigEndian, uint32(cfg.segmentSize))
+	binary.Write(header, binary.BigEndian, uint16(len(wrapped)))
+	header.Write(wrapped)
+	header.Write(prefix)
+
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{
+		w:      w,
+		aead:   aead,
+		header: header.Bytes(),
+		prefix: prefix,
+		buf:    make([]byte, 0, cfg.segmentSize),
+		out:    make([]byte, 0, cfg.segmentSize+streamTagSize),
+	}, nil
+}
+
+func (this *encryptWriter) Write(p []byte) (int, error) {
+	if this.err != nil {
+		return 0, this.err
+	}
+
+	var n int
+	for len(p) > 0 {
+		// a full segment is only sealed once more data comes, because the last
+		// segment must be sealed with the last flag.
+		if len(this.buf) == cap(this.buf) {
+			if err := this.seal(false); err != nil {
+				return n, err
+			}
+		}
+
+		m := copy(this.buf[len(this.buf):cap(this.buf)], p)
+		this.buf = this.buf[:len(this.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+// Close seals the last segment.
+func (this *encryptWriter) Close() error {
+	if this.err != nil {
+		if this.err == ErrStreamClosed {
+			return nil
+		}
+		return this.err
+	}
+
+	if err := this.seal(true); err != nil {
+		return err
+	}
+	this.err = ErrStreamClosed
+	return nil
+}
+
+func (this *encryptWriter) seal(last bool) error {
+	if this.counter == math.MaxUint32 {
+		this.err = ErrStreamTooLong
+		return this.err
+	}
+
+	this.out = this.aead.Seal(this.out[:0], streamNonce(this.prefix, this.counter, last), this.buf, this.header)
+	if _, err := this.w.Write(this.out); err != nil {
+		this.err = err
+		return err
+	}
+
+	this.counter++
+	this.buf = this.buf[:0]
+	return nil
+}
+
+// NewDecryptReader returns a reader decrypting the stream written by an encrypt
+// writer from r, the stream key is unwrapped by crypter. The reader returns
+// ErrAuthentication if the stream was tampered, and ErrStreamTruncated if it
+// ends before the last segment.
+func NewDecryptReader(r io.Reader, crypter Decrypter) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	fixed := make([]byte, streamFixedHeader)
+	if _, err := io.ReadFull(br, fixed); err != nil {
+		return nil, ErrStreamFormat
+	}
+	if string(fixed[:len(streamMagic)]) != streamMagic {
+		return nil, ErrStreamFormat
+	}
+	if fixed[len(streamMagic)] != streamVersion {
+		return nil, ErrStreamFormat
+	}
+
+	segmentSize := int(binary.BigEndian.Uint32(fixed[len(streamMagic)+1:]))
+	if segmentSize <= 0 || segmentSize > MaxSegmentSize {
+		return nil, ErrSegmentSize
+	}
+
+	wrappedLen := int(binary.BigEndian.Uint16(fixed[len(streamMagic)+5:]))
+	rest := make([]byte, wrappedLen+streamPrefixSize)
+	if _, err := io.ReadFull(br, rest); err != nil {
+		return nil, ErrStreamFormat
+	}
+
+	key, err := crypter.Decrypt(rest[:wrappedLen])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newStreamAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		r:       br,
+		aead:    aead,
+		header:  append(fixed, rest...),
+		prefix:  rest[wrappedLen:],
+		segment: make([]byte, segmentSize+streamTagSize),
+		buf:     make([]byte, 0, segmentSize),
+	}, nil
+}
+
+func (this *decryptReader) Read(p []byte) (int, error) {
+	for len(this.plain) == 0 {
+		if this.err != nil {
+			return 0, this.err
+		}
+		if this.done {
+			return 0, io.EOF
+		}
+		this.err = this.open()
+	}
+
+	n := copy(p, this.plain)
+	this.plain = this.plain[n:]
+	return n, nil
+}
+
+// open reads and opens the next segment.
+func (this *decryptReader) open() error {
+	n, err := io.ReadFull(this.r, this.segment)
+	switch err {
+	case nil, io.ErrUnexpectedEOF:
+	case io.EOF:
+		return ErrStreamTruncated
+	default:
+		return err
+	}
+
+	// the segment is the last one if the stream ends with it.
+	last := n < len(this.segment)
+	if !last {
+		if _, err := this.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if n < streamTagSize {
+		return ErrStreamTruncated
+	}
+	if this.counter == math.MaxUint32 {
+		return ErrStreamTooLong
+	}
+
+	segment := this.segment[:n]
+	plain, err := this.aead.Open(this.buf[:0], streamNonce(this.prefix, this.counter, last), segment, this.header)
+	if err != nil {
+		// a segment sealed as not the last one means the end of the stream was cut.
+		if last {
+			if _, err := this.aead.Open(this.buf[:0], streamNonce(this.prefix, this.counter, false), segment, this.header); err == nil {
+				return ErrStreamTruncated
+			}
+		}
+		return ErrAuthentication
+	}
+
+	this.counter++
+	this.plain = plain
+	this.done = last
+	return nil
+}
+
+func newStreamAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != streamKeySize {
+		return nil, ErrKeySize
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func streamNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, streamPrefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[streamPrefixSize:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
@@ -0,0 +1,82 @@
+package codec_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+func encryptStream(t *testing.T, crypter codec.Crypter, data []byte, segmentSize int) []byte {
+	var buf bytes.Buffer
+	w, err := codec.NewEncryptWriter(&buf, crypter, codec.WithSegmentSize(segmentSize))
+	assert.Nil(t, err)
+
+	// write in odd sized pieces to cross the segment boundaries.
+	for len(data) > 0 {
+		n := 7
+		if n > len(data) {
+			n = len(data)
+		}
+		_, err = w.Write(data[:n])
+		assert.Nil(t, err)
+		data = data[n:]
+	}
+	assert.Nil(t, w.Close())
+	assert.Nil(t, w.Close())
+	return buf.Bytes()
+}
+
+func decryptStream(crypter codec.Crypter, enc []byte) ([]byte, error) {
+	r, err := codec.NewDecryptReader(bytes.NewReader(enc), crypter)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestStream(t *testing.T) {
+	kek, err := codec.NewAESGCM(bytes.Repeat([]byte{1}, 32))
+	assert.Nil(t, err)
+
+	for _, n := range []int{0, 1, 63, 64, 65, 128, 1000} {
+		data := bytes.Repeat([]byte{'x'}, n)
+		enc := encryptStream(t, kek, data, 64)
+
+		dec, err := decryptStream(kek, enc)
+		assert.Nil(t, err)
+		assert.Equal(t, data, append([]byte{}, dec...), n)
+	}
+}
+
+func TestStreamTampered(t *testing.T) {
+	kek, err := codec.NewRsa(pubKey, priKey)
+	assert.Nil(t, err)
+	// 5 full segments and a last one of 10 bytes.
+	data := bytes.Repeat([]byte{'x'}, 5*64+10)
+	enc := encryptStream(t, kek, data, 64)
+	segment := 64 + 16
+
+	// cut at a segment boundary.
+	_, err = decryptStream(kek, enc[:len(enc)-10-16])
+	assert.Equal(t, codec.ErrStreamTruncated, err)
+	_, err = decryptStream(kek, enc[:len(enc)-10-16-segment])
+	assert.Equal(t, codec.ErrStreamTruncated, err)
+
+	// cut within a segment.
+	_, err = decryptStream(kek, enc[:len(enc)-5])
+	assert.Equal(t, codec.ErrAuthentication, err)
+
+	tampered := append([]byte{}, enc...)
+	tampered[len(tampered)-10-16-segment] ^= 1
+	_, err = decryptStream(kek, tampered)
+	assert.Equal(t, codec.ErrAuthentication, err)
+
+	_, err = decryptStream(kek, []byte("GCST"))
+	assert.Equal(t, codec.ErrStreamFormat, err)
+
+	_, err = codec.NewEncryptWriter(io.Discard, kek, codec.WithSegmentSize(0))
+	assert.Equal(t, codec.ErrSegmentSize, err)
+}
@@ -2,7 +2,7 @@
  * @Author: cnzf1
  * @Date: 2023-03-28 14:36:32
  * @LastEditors: cnzf1
- * @LastEditTime: 2023-04-03 16:38:08
+ * @LastEditTime: 2026-10-17 22:10:26
  * @Description:
  */
 package mapx
@@ -17,12 +17,44 @@ import (
 )
 
 type ExpiredMap struct {
-	m        sync.Map
+	m sync.Map
+	// serializes the writes, so that an expired item is removed only if its
+	// key wasn't set again meanwhile.
+	lock     sync.Mutex
 	tick     time.Duration
 	capacity int64
 	size     atomic.Int64
 	stop     chan bool
 	delFn    DelCallBack
+	evictFn  EvictCallBack
+	codec    ValueCodec
+	hook     EMStatsHook
+	hits     atomic.Uint64
+	misses   atomic.Uint64
+	expired  atomic.Uint64
+	deleted  atomic.Uint64
+	replaced atomic.Uint64
+}
+
+// EMStats is a snapshot of the counters of an ExpiredMap.
+type EMStats struct {
+	Hits    uint64
+	Misses  uint64
+	Expired uint64
+	Deleted uint64
+	// Replaced is the number of values overwritten by Set.
+	Replaced uint64
+	Size     int64
+}
+
+// EMStatsHook is notified of the map events as they happen,
+// it's meant to feed a metrics registry. The methods must not block.
+type EMStatsHook interface {
+	OnHit()
+	OnMiss()
+	OnExpire()
+	OnDelete()
+	OnReplace()
 }
 
 type mapItem struct {
@@ -32,8 +64,11 @@ type mapItem struct {
 }
 
 type EMConfig struct {
-	tick  time.Duration
-	delFn DelCallBack
+	tick    time.Duration
+	delFn   DelCallBack
+	evictFn EvictCallBack
+	codec   ValueCodec
+	hook    EMStatsHook
 }
 
 type EMOption func(*EMConfig)
@@ -51,10 +86,29 @@ func WithDelCallback(fn DelCallBack) EMOption {
 		e.delFn = fn
 	}
 }
+
+// EvictCallBack is called with the key and the value removed, whether expired or deleted.
+type EvictCallBack func(key string, value lang.AnyType)
+
+// WithEvictCallback sets the function called with the removed values, to release them.
+func WithEvictCallback(fn EvictCallBack) EMOption {
+	return func(e *EMConfig) {
+		e.evictFn = fn
+	}
+}
+
+// WithStatsHook sets the hook notified of the hits, misses, expirations, deletions and replacements.
+func WithStatsHook(hook EMStatsHook) EMOption {
+	return func(e *EMConfig) {
+		e.hook = hook
+	}
+}
+
 func NewExpiredMap(opts ...EMOption) *ExpiredMap {
 	cfg := &EMConfig{
 		tick:  time.Second,
 		delFn: nil,
+		codec: jsonCodec{},
 	}
 
 	for _, opt := range opts {
@@ -62,9 +116,12 @@ func NewExpiredMap(opts ...EMOption) *ExpiredMap {
 	}
 
 	c := &ExpiredMap{
-		tick:  cfg.tick,
-		stop:  make(chan bool),
-		delFn: cfg.delFn,
+		tick:    cfg.tick,
+		stop:    make(chan bool),
+		delFn:   cfg.delFn,
+		evictFn: cfg.evictFn,
+		codec:   cfg.codec,
+		hook:    cfg.hook,
 	}
 
 	c.check()
@@ -82,7 +139,7 @@ func (c *ExpiredMap) check() {
 					v, ok := value.(*mapItem)
 					if ok && v != nil && time.Now().After(v.expire.Load()) {
 						tkey := key.(string)
-						c.Delete(tkey)
+						c.removeItem(tkey, v)
 					}
 					return true
 				})
@@ -100,8 +157,21 @@ func (c *ExpiredMap) Set(key string, value lang.AnyType, ttl time.Duration) bool
 	}
 
 	v.expire.Store(time.Now().Add(ttl))
-	c.m.Store(key, v)
-	c.size.Inc()
+	c.lock.Lock()
+	_, loaded := c.m.LoadOrStore(key, v)
+	if loaded {
+		c.m.Store(key, v)
+	} else {
+		c.size.Inc()
+	}
+	c.lock.Unlock()
+
+	if loaded {
+		c.replaced.Inc()
+		if c.hook != nil {
+			c.hook.OnReplace()
+		}
+	}
 	return true
 }
 
@@ -109,30 +179,97 @@ func (c *ExpiredMap) Get(key string) (value lang.AnyType, ok bool) {
 	var v any
 	v, ok = c.m.Load(key)
 	if !ok {
+		c.miss()
 		return
 	}
 
 	v2, ok := v.(*mapItem)
 	if !ok || v2 == nil {
+		c.miss()
 		return
 	}
 
 	if time.Now().After(v2.expire.Load()) {
 		ok = false
-		c.Delete(key)
+		c.miss()
+		c.removeItem(key, v2)
 		return
 	}
 
+	c.hits.Inc()
+	if c.hook != nil {
+		c.hook.OnHit()
+	}
 	value = v2.value
 	return
 }
 
+func (c *ExpiredMap) miss() {
+	c.misses.Inc()
+	if c.hook != nil {
+		c.hook.OnMiss()
+	}
+}
+
 func (c *ExpiredMap) Delete(key string) {
-	c.m.Delete(key)
+	c.remove(key)
+}
+
+// remove deletes key if it exists, and counts it as deleted.
+func (c *ExpiredMap) remove(key string) {
+	c.lock.Lock()
+	v, ok := c.m.LoadAndDelete(key)
+	c.lock.Unlock()
+	if !ok {
+		return
+	}
+
+	c.deleted.Inc()
+	if c.hook != nil {
+		c.hook.OnDelete()
+	}
+	c.evict(key, v.(*mapItem))
+}
+
+// removeItem deletes the expired item of key, unless key was set again meanwhile.
+func (c *ExpiredMap) removeItem(key string, item *mapItem) {
+	c.lock.Lock()
+	v, ok := c.m.Load(key)
+	if ok && v == item {
+		c.m.Delete(key)
+	}
+	c.lock.Unlock()
+	if !ok || v != item {
+		return
+	}
+
+	c.expired.Inc()
+	if c.hook != nil {
+		c.hook.OnExpire()
+	}
+	c.evict(key, item)
+}
+
+func (c *ExpiredMap) evict(key string, item *mapItem) {
 	c.size.Dec()
 	if c.delFn != nil {
 		c.delFn(key)
 	}
+	if c.evictFn != nil {
+		c.evictFn(key, item.value)
+	}
+}
+
+// Stats returns the counters of the map since it was created.
+func (c *ExpiredMap) Stats() EMStats {
+	return EMStats{
+		Hits:     c.hits.Load(),
+		Misses:   c.misses.Load(),
+		Expired:  c.expired.Load(),
+		Deleted:  c.deleted.Load(),
+		Replaced: c.replaced.Load(),
+		Size:     c.size.Load(),
+	}
 }
 
 func (c *ExpiredMap) Size() int64 {
@@ -152,7 +289,7 @@ func (c *ExpiredMap) TTL(key string) time.Duration {
 
 	now := time.Now()
 	if now.After(v2.expire.Load()) {
-		c.Delete(key)
+		c.removeItem(key, v2)
 		return -1
 	}
 
@@ -168,7 +305,7 @@ func (c *ExpiredMap) Foreach(fn func(key string, value lang.AnyType)) {
 
 		if time.Now().After(v.expire.Load()) {
 			tkey := key.(string)
-			c.Delete(tkey)
+			c.removeItem(tkey, v)
 			return true
 		}
 		tkey := key.(string)
@@ -0,0 +1,31 @@
+package mapx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cnzf1/gocore/lang"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiredMapRemoveRefreshed(t *testing.T) {
+	var evicted []lang.AnyType
+	m := NewExpiredMap(WithTick(time.Hour), WithEvictCallback(func(key string, value lang.AnyType) {
+		evicted = append(evicted, value)
+	}))
+	defer m.Close()
+
+	m.Set("a", 1, -time.Second)
+	v, _ := m.m.Load("a")
+	stale := v.(*mapItem)
+
+	// the key is set again between the expiry check and the removal.
+	m.Set("a", 2, time.Hour)
+	m.removeItem("a", stale)
+
+	value, ok := m.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+	assert.Equal(t, int64(1), m.Size())
+	assert.Empty(t, evicted)
+}
@@ -8,6 +8,7 @@
 package mapx_test
 
 import (
+	"bytes"
 	"fmt"
 	"strconv"
 	"sync"
@@ -15,6 +16,8 @@ import (
 	"time"
 
 	"github.com/cnzf1/gocore/collection/mapx"
+	"github.com/cnzf1/gocore/lang"
+	"github.com/stretchr/testify/assert"
 )
 
 type cacheItem struct {
@@ -54,3 +57,116 @@ func TestNewExpiredMap(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestExpiredMapSnapshot(t *testing.T) {
+	em := mapx.NewExpiredMap()
+	defer em.Close()
+	em.Set("a", "foo", time.Minute)
+	em.Set("b", 1, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	var buf bytes.Buffer
+	assert.Nil(t, em.Snapshot(&buf))
+
+	restored := mapx.NewExpiredMap()
+	defer restored.Close()
+	assert.Nil(t, restored.Restore(&buf))
+
+	v, ok := restored.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "foo", v)
+	assert.True(t, restored.TTL("a") > 50*time.Second)
+	_, ok = restored.Get("b")
+	assert.False(t, ok)
+}
+
+func TestExpiredMapStats(t *testing.T) {
+	em := mapx.NewExpiredMap()
+	defer em.Close()
+	em.Set("a", 1, time.Minute)
+	em.Set("a", 2, time.Minute)
+	em.Set("b", 1, time.Millisecond)
+	em.Set("c", 1, time.Minute)
+	time.Sleep(10 * time.Millisecond)
+
+	em.Get("a")
+	em.Get("b")
+	em.Get("d")
+	em.Delete("c")
+	em.Delete("c")
+
+	stats := em.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Expired)
+	assert.Equal(t, uint64(1), stats.Deleted)
+	assert.Equal(t, uint64(1), stats.Replaced)
+	assert.Equal(t, int64(1), stats.Size)
+}
+
+type countingHook struct {
+	lock   sync.Mutex
+	events map[string]int
+}
+
+func (h *countingHook) add(event string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.events[event]++
+}
+
+func (h *countingHook) OnHit()     { h.add("hit") }
+func (h *countingHook) OnMiss()    { h.add("miss") }
+func (h *countingHook) OnExpire()  { h.add("expire") }
+func (h *countingHook) OnDelete()  { h.add("delete") }
+func (h *countingHook) OnReplace() { h.add("replace") }
+
+func TestExpiredMapStatsHook(t *testing.T) {
+	hook := &countingHook{events: make(map[string]int)}
+	em := mapx.NewExpiredMap(mapx.WithStatsHook(hook))
+	defer em.Close()
+	em.Set("a", 1, time.Minute)
+	em.Set("a", 2, time.Minute)
+	em.Set("a", 3, time.Minute)
+	em.Set("b", 1, time.Millisecond)
+	em.Set("c", 1, time.Minute)
+	time.Sleep(10 * time.Millisecond)
+
+	em.Get("a")
+	em.Get("a")
+	em.Get("b")
+	em.Get("d")
+	em.Delete("c")
+	em.Delete("c")
+
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	assert.Equal(t, map[string]int{
+		"hit":     2,
+		"miss":    2,
+		"expire":  1,
+		"delete":  1,
+		"replace": 2,
+	}, hook.events)
+	assert.Equal(t, uint64(2), em.Stats().Replaced)
+}
+
+func TestExpiredMapEvictCallback(t *testing.T) {
+	evicted := make(map[string]lang.AnyType)
+	var lock sync.Mutex
+	m := mapx.NewExpiredMap(mapx.WithTick(10*time.Millisecond), mapx.WithEvictCallback(func(key string, value lang.AnyType) {
+		lock.Lock()
+		defer lock.Unlock()
+		evicted[key] = value
+	}))
+	defer m.Close()
+
+	m.Set("a", 1, 20*time.Millisecond)
+	m.Set("b", 2, time.Hour)
+	m.Delete("b")
+	time.Sleep(50 * time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, map[string]lang.AnyType{"a": 1, "b": 2}, evicted)
+}
@@ -0,0 +1,105 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 20:45:10
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 20:45:10
+ * @Description:
+ */
+package mapx
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/cnzf1/gocore/internal/snapshot"
+	"github.com/cnzf1/gocore/lang"
+)
+
+// ValueCodec encodes the values of the ExpiredMap snapshots.
+type ValueCodec interface {
+	Marshal(value lang.AnyType) ([]byte, error)
+	Unmarshal(data []byte) (lang.AnyType, error)
+}
+
+// jsonCodec is the default ValueCodec, the restored values are
+// the generic types of encoding/json, such as map[string]interface{}.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(value lang.AnyType) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonCodec) Unmarshal(data []byte) (lang.AnyType, error) {
+	var value lang.AnyType
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// WithValueCodec sets the codec of the values in the snapshots.
+func WithValueCodec(codec ValueCodec) EMOption {
+	return func(e *EMConfig) {
+		e.codec = codec
+	}
+}
+
+// Snapshot writes the unexpired items to w, with their expiration time.
+func (c *ExpiredMap) Snapshot(w io.Writer) error {
+	sw, err := snapshot.NewWriter(w)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	c.m.Range(func(key, value any) bool {
+		v, ok := value.(*mapItem)
+		if !ok || v == nil || now.After(v.expire.Load()) {
+			return true
+		}
+
+		var data []byte
+		if data, err = c.codec.Marshal(v.value); err != nil {
+			return false
+		}
+		err = sw.Write(snapshot.Entry{
+			Key:    []byte(v.key),
+			Value:  data,
+			Expire: v.expire.Load().UnixNano(),
+		})
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sw.Close()
+}
+
+// Restore sets the items of a snapshot written by Snapshot,
+// the items expired since the snapshot are skipped.
+func (c *ExpiredMap) Restore(r io.Reader) error {
+	sr, err := snapshot.NewReader(r)
+	if err != nil {
+		return err
+	}
+
+	for {
+		e, err := sr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		ttl := time.Until(time.Unix(0, e.Expire))
+		if ttl <= 0 {
+			continue
+		}
+
+		value, err := c.codec.Unmarshal(e.Value)
+		if err != nil {
+			return err
+		}
+		c.Set(string(e.Key), value, ttl)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 00:12:50
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 00:12:50
+ * @Description:
+ */
+package filex
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/cnzf1/gocore/codec"
+)
+
+// EncryptFile encrypts the specified file in place with codec.NewEncryptWriter,
+// the file is replaced only once it's fully encrypted.
+func EncryptFile(filename string, crypter codec.Encrypter, opts ...codec.StreamOption) error {
+	return rewriteFile(filename, func(dst io.Writer, src io.Reader) error {
+		w, err := codec.NewEncryptWriter(dst, crypter, opts...)
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.Copy(w, src); err != nil {
+			return err
+		}
+		return w.Close()
+	})
+}
+
+// DecryptFile decrypts in place the specified file encrypted by EncryptFile,
+// the file is left untouched if it was tampered or truncated.
+func DecryptFile(filename string, crypter codec.Decrypter) error {
+	return rewriteFile(filename, func(dst io.Writer, src io.Reader) error {
+		r, err := codec.NewDecryptReader(src, crypter)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(dst, r)
+		return err
+	})
+}
+
+// rewriteFile writes the content transformed by fn into a temporary file,
+// then renames it to filename, keeping its permissions.
+func rewriteFile(filename string, fn func(dst io.Writer, src io.Reader) error) (err error) {
+	src, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = fn(tmp, src); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
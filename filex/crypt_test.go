@@ -0,0 +1,43 @@
+package filex_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/cnzf1/gocore/filex"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptFile(t *testing.T) {
+	crypter, err := codec.NewAESGCM(bytes.Repeat([]byte{1}, 32))
+	assert.Nil(t, err)
+
+	filename := filepath.Join(t.TempDir(), "backup.dat")
+	content := bytes.Repeat([]byte("backup"), 10000)
+	assert.Nil(t, os.WriteFile(filename, content, 0600))
+
+	assert.Nil(t, filex.EncryptFile(filename, crypter, codec.WithSegmentSize(1024)))
+	enc, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.NotEqual(t, content, enc)
+
+	// a truncated file is left untouched.
+	assert.Nil(t, os.WriteFile(filename, enc[:len(enc)-100], 0600))
+	assert.Equal(t, codec.ErrAuthentication, filex.DecryptFile(filename, crypter))
+	truncated, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, enc[:len(enc)-100], truncated)
+
+	assert.Nil(t, os.WriteFile(filename, enc, 0600))
+	assert.Nil(t, filex.DecryptFile(filename, crypter))
+	dec, err := os.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, content, dec)
+
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+}
@@ -0,0 +1,74 @@
+package httpx_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/cnzf1/gocore/httpx"
+	"github.com/stretchr/testify/assert"
+)
+
+type message struct {
+	Text string
+}
+
+func TestPostWithContentType(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg message
+		if err := httpx.DecodeBody(r, &msg); err != nil {
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+		msg.Text += "!"
+		assert.Nil(t, httpx.WriteBody(w, http.StatusOK, r.Header.Get("Content-Type"), msg))
+	}))
+	defer srv.Close()
+
+	for _, contentType := range []string{codec.ContentTypeJSON, codec.ContentTypeMsgPack, "application/x-gob+gzip"} {
+		data, err := httpx.Client().PostWithContentType(srv.URL, contentType, message{Text: "hello"})
+		assert.Nil(t, err)
+
+		var msg message
+		assert.Nil(t, codec.Unmarshal(contentType, data, &msg))
+		assert.Equal(t, "hello!", msg.Text)
+	}
+
+	_, err := httpx.Client().PostWithContentType(srv.URL, "application/xml", message{})
+	assert.Equal(t, codec.ErrContentType, err)
+}
+
+func TestDecodeBodyMaxSize(t *testing.T) {
+	text := strings.Repeat("hello", 100)
+	for _, contentType := range []string{codec.ContentTypeJSON, "application/json+gzip"} {
+		data, err := codec.Marshal(contentType, message{Text: text})
+		assert.Nil(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+		r.Header.Set("Content-Type", contentType)
+		var msg message
+		assert.Nil(t, httpx.DecodeBodyWithMaxSize(r, &msg, 1000))
+		assert.Equal(t, text, msg.Text)
+
+		r = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+		r.Header.Set("Content-Type", contentType)
+		assert.NotNil(t, httpx.DecodeBodyWithMaxSize(r, &msg, 100))
+	}
+
+	// the gzip body is small, but it's decompressed beyond the max size.
+	data, err := codec.Marshal("application/json+gzip", message{Text: strings.Repeat("hello", 1000)})
+	assert.Nil(t, err)
+	assert.Less(t, len(data), 1000)
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	r.Header.Set("Content-Type", "application/json+gzip")
+	var msg message
+	assert.Equal(t, codec.ErrTooLarge, httpx.DecodeBodyWithMaxSize(r, &msg, 1000))
+
+	data, err = codec.Marshal(codec.ContentTypeJSON, message{Text: strings.Repeat("hello", 1000)})
+	assert.Nil(t, err)
+	r = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	assert.Equal(t, httpx.ErrBodyTooLarge, httpx.DecodeBodyWithMaxSize(r, &msg, 1000))
+}
@@ -9,15 +9,19 @@ package httpx
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"io"
 	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/cnzf1/gocore/codec"
 )
 
 type HttpxClient struct {
@@ -26,6 +30,10 @@ type HttpxClient struct {
 
 type httpxOption func(r *http.Request)
 
+// optionErrorKey is the context key of the error of an option, the request
+// isn't sent if an option fails.
+type optionErrorKey struct{}
+
 const defaultTimeout = 5 * time.Second
 
 var client *HttpxClient
@@ -75,6 +83,23 @@ func Client() *HttpxClient {
 	return client
 }
 
+// applyOptions applies opts to r in order, and returns the first error an option
+// recorded by setOptionError.
+func applyOptions(r *http.Request, opts []httpxOption) error {
+	for _, opt := range opts {
+		opt(r)
+		if err, ok := r.Context().Value(optionErrorKey{}).(error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// setOptionError records the error of an option applied to r, so that r isn't sent.
+func setOptionError(r *http.Request, err error) {
+	*r = *r.WithContext(context.WithValue(r.Context(), optionErrorKey{}, err))
+}
+
 func (h *HttpxClient) GetToMap(url string, opts ...httpxOption) (map[string]interface{}, error) {
 	return h.GetToMapWithTimeOut(url, defaultTimeout, opts...)
 }
@@ -86,8 +111,8 @@ func (h *HttpxClient) GetToMapWithTimeOut(url string, timeout time.Duration, opt
 		return nil, errors.New("new request is fail ")
 	}
 
-	for _, opt := range opts {
-		opt(req)
+	if err = applyOptions(req, opts); err != nil {
+		return nil, err
 	}
 
 	h.cli.Timeout = timeout
@@ -122,8 +147,8 @@ func (h *HttpxClient) GetWithTimeOut(url string, timeout time.Duration, opts ...
 		return nil, errors.New("new request is fail ")
 	}
 
-	for _, opt := range opts {
-		opt(req)
+	if err = applyOptions(req, opts); err != nil {
+		return nil, err
 	}
 
 	h.cli.Timeout = timeout
@@ -158,8 +183,8 @@ func (h *HttpxClient) PostToMapWithTimeOut(url string, body interface{}, timeout
 		return nil, errors.New("new request is fail: %v \n")
 	}
 
-	for _, opt := range opts {
-		opt(req)
+	if err = applyOptions(req, opts); err != nil {
+		return nil, err
 	}
 
 	h.cli.Timeout = timeout
@@ -204,8 +229,8 @@ func (h *HttpxClient) PostWithOptions(url string, timeout time.Duration, body in
 		return nil, errors.New("new request is fail: %v \n")
 	}
 
-	for _, opt := range opts {
-		opt(req)
+	if err = applyOptions(req, opts); err != nil {
+		return nil, err
 	}
 
 	h.cli.Timeout = timeout
@@ -219,6 +244,78 @@ func (h *HttpxClient) PostWithOptions(url string, timeout time.Duration, body in
 	return ioutil.ReadAll(resp.Body)
 }
 
+// PostWithContentType posts body encoded by the codec.Marshaler of contentType,
+// such as application/msgpack or application/json+gzip.
+func (h *HttpxClient) PostWithContentType(url, contentType string, body interface{}, opts ...httpxOption) ([]byte, error) {
+	data, err := codec.Marshal(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	if err = applyOptions(req, opts); err != nil {
+		return nil, err
+	}
+
+	h.cli.Timeout = defaultTimeout
+
+	resp, err := h.cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// DecodeBody decodes the body of r into v by the codec.Marshaler of its content
+// type, the body is decoded as JSON if the content type is missing. The body is
+// limited to codec.DefaultMaxDecompressedSize, decompressed or not.
+func DecodeBody(r *http.Request, v interface{}) error {
+	return DecodeBodyWithMaxSize(r, v, codec.DefaultMaxDecompressedSize)
+}
+
+// DecodeBodyWithMaxSize is DecodeBody with the body limited to maxSize, it fails
+// with ErrBodyTooLarge or codec.ErrTooLarge if the body is decompressed beyond.
+func DecodeBodyWithMaxSize(r *http.Request, v interface{}, maxSize int64) error {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = codec.ContentTypeJSON
+	}
+
+	m, err := codec.GetMarshaler(contentType, codec.WithMaxDecompressedSize(maxSize))
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > maxSize {
+		return ErrBodyTooLarge
+	}
+	return m.Unmarshal(data, v)
+}
+
+// WriteBody writes v encoded by the codec.Marshaler of contentType with status.
+func WriteBody(w http.ResponseWriter, status int, contentType string, v interface{}) error {
+	data, err := codec.Marshal(contentType, v)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	return err
+}
+
 // GetIP returns request real ip.
 func GetIP(r *http.Request) string {
 	ip := r.Header.Get("X-Real-IP")
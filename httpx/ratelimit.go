@@ -0,0 +1,245 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 05:48:26
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 05:48:26
+ * @Description: 限流中间件
+ */
+package httpx
+
+import (
+	"context"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cnzf1/gocore/limit"
+)
+
+const (
+	HeaderRetryAfter         = "Retry-After"
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+)
+
+type (
+	// KeyFunc returns the key of the request the limit applies to.
+	KeyFunc func(r *http.Request) string
+
+	// RateLimitOption customizes the RateLimit middleware.
+	RateLimitOption func(*rateLimiter)
+
+	rateLimiter struct {
+		limiter     *limit.KeyedLimiter
+		keyFunc     KeyFunc
+		allowNets   []*net.IPNet
+		trustedNets []*net.IPNet
+		allowFunc   func(r *http.Request) bool
+		denied      http.Handler
+	}
+
+	clientIPKey struct{}
+)
+
+// KeyByIP keys the requests by the ip of the caller. The ip is taken from the
+// X-Forwarded-For header only if the request comes from a proxy trusted by
+// WithTrustedProxies, from the remote address otherwise.
+func KeyByIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// KeyByHeader keys the requests by the header name, such as an API key,
+// the requests without it are keyed by ip.
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		if key := r.Header.Get(name); key != "" {
+			return name + ":" + key
+		}
+		return KeyByIP(r)
+	}
+}
+
+// WithKeyFunc sets how the requests are keyed, KeyByIP by default.
+func WithKeyFunc(fn KeyFunc) RateLimitOption {
+	return func(l *rateLimiter) {
+		l.keyFunc = fn
+	}
+}
+
+// WithAllowList lets the callers of the ips or the CIDRs, such as "10.0.0.0/8",
+// bypass the limit. The invalid ones are logged and skipped.
+func WithAllowList(ips ...string) RateLimitOption {
+	return func(l *rateLimiter) {
+		l.allowNets = append(l.allowNets, parseNets(ips)...)
+	}
+}
+
+// WithTrustedProxies sets the ips or the CIDRs of the proxies whose X-Forwarded-For
+// header is trusted, the header of the other callers is ignored since it can be
+// forged. The caller is the last address of the header which isn't a trusted
+// proxy, the addresses before it are set by the caller itself. The invalid ips
+// are logged and skipped.
+func WithTrustedProxies(ips ...string) RateLimitOption {
+	return func(l *rateLimiter) {
+		l.trustedNets = append(l.trustedNets, parseNets(ips)...)
+	}
+}
+
+// WithAllowFunc lets the requests fn returns true for bypass the limit.
+func WithAllowFunc(fn func(r *http.Request) bool) RateLimitOption {
+	return func(l *rateLimiter) {
+		l.allowFunc = fn
+	}
+}
+
+// WithDeniedHandler sets the handler of the requests over the limit, once the
+// headers set. It answers 429 Too Many Requests by default.
+func WithDeniedHandler(h http.Handler) RateLimitOption {
+	return func(l *rateLimiter) {
+		l.denied = h
+	}
+}
+
+// RateLimit returns a middleware limiting the requests of each key with limiter.
+// The responses carry the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers, and the Retry-After header once the limit is reached.
+func RateLimit(limiter *limit.KeyedLimiter, opts ...RateLimitOption) func(http.Handler) http.Handler {
+	l := &rateLimiter{
+		limiter: limiter,
+		keyFunc: KeyByIP,
+		denied: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := l.clientIP(r)
+			if l.allowed(r, ip) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip))
+
+			ok, usage := l.limiter.AllowUsage(l.keyFunc(r))
+
+			remaining := usage.Limit - usage.Used
+			if remaining < 0 {
+				remaining = 0
+			}
+			header := w.Header()
+			header.Set(HeaderRateLimitLimit, strconv.FormatInt(usage.Limit, 10))
+			header.Set(HeaderRateLimitRemaining, strconv.FormatInt(remaining, 10))
+			header.Set(HeaderRateLimitReset, formatSeconds(usage.Reset))
+
+			if !ok {
+				// the limit may be freed meanwhile, the caller still waits a little.
+				retryAfter := usage.RetryAfter
+				if retryAfter < time.Second {
+					retryAfter = time.Second
+				}
+				header.Set(HeaderRetryAfter, formatSeconds(retryAfter))
+				l.denied.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allowed reports whether the request of the caller at ip bypasses the limit.
+func (l *rateLimiter) allowed(r *http.Request, ip string) bool {
+	if l.allowFunc != nil && l.allowFunc(r) {
+		return true
+	}
+	return containsIP(l.allowNets, ip)
+}
+
+// clientIP returns the ip of the caller, walking the X-Forwarded-For header from
+// the right as long as the hops are trusted proxies. It stops at a malformed
+// entry, and returns the last trusted hop then.
+func (l *rateLimiter) clientIP(r *http.Request) string {
+	ip := remoteIP(r)
+	if !containsIP(l.trustedNets, ip) {
+		return ip
+	}
+
+	// several headers are a single list, in order.
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !containsIP(l.trustedNets, hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// remoteIP returns the ip of the remote address of r, "" if it's malformed.
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil || net.ParseIP(ip) == nil {
+		return ""
+	}
+	return ip
+}
+
+func containsIP(nets []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range nets {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNets parses the ips or the CIDRs, the invalid ones are logged and skipped.
+func parseNets(ips []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, ip := range ips {
+		if !strings.Contains(ip, "/") {
+			if strings.Contains(ip, ":") {
+				ip += "/128"
+			} else {
+				ip += "/32"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(ip)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// formatSeconds formats d in whole seconds, rounded up.
+func formatSeconds(d time.Duration) string {
+	if d <= 0 {
+		return "0"
+	}
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,147 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cnzf1/gocore/httpx"
+	"github.com/cnzf1/gocore/limit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	limiter := limit.NewKeyedLimiter(limit.WindowLimitTemplate(60), 2)
+	defer limiter.Close()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := httpx.RateLimit(limiter,
+		httpx.WithKeyFunc(httpx.KeyByHeader("X-Api-Key")),
+		httpx.WithAllowList("10.0.0.0/8", "192.168.1.1", "bad"),
+	)(ok)
+
+	serve := func(remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			r.Header.Set("X-Api-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("1.2.3.4:1000", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(httpx.HeaderRateLimitLimit))
+	assert.Equal(t, "1", w.Header().Get(httpx.HeaderRateLimitRemaining))
+	assert.Equal(t, "60", w.Header().Get(httpx.HeaderRateLimitReset))
+
+	assert.Equal(t, http.StatusOK, serve("1.2.3.4:1001", "").Code)
+	w = serve("1.2.3.4:1002", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get(httpx.HeaderRateLimitRemaining))
+	assert.Equal(t, "60", w.Header().Get(httpx.HeaderRetryAfter))
+
+	// the API key has its own limit.
+	w = serve("1.2.3.4:1003", "key")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(httpx.HeaderRetryAfter))
+
+	// the internal callers bypass the limit.
+	for i := 0; i < 5; i++ {
+		w = serve("10.1.2.3:1000", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(httpx.HeaderRateLimitLimit))
+		assert.Equal(t, http.StatusOK, serve("192.168.1.1:1000", "").Code)
+	}
+	assert.Equal(t, http.StatusOK, serve("192.168.1.2:1000", "key").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve("192.168.1.2:1000", "key").Code)
+}
+
+func TestRateLimitDenied(t *testing.T) {
+	limiter := limit.NewKeyedLimiter(limit.TokenBucketTemplate(time.Second), 1)
+	defer limiter.Close()
+
+	handler := httpx.RateLimit(limiter,
+		httpx.WithAllowFunc(func(r *http.Request) bool {
+			return r.Header.Get("X-Internal") == "true"
+		}),
+		httpx.WithDeniedHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			httpx.WriteBody(w, http.StatusTooManyRequests, "application/json", message{Text: "slow down"})
+		})),
+	)(http.NotFoundHandler())
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get(httpx.HeaderRetryAfter))
+	assert.JSONEq(t, `{"Text":"slow down"}`, w.Body.String())
+
+	r.Header.Set("X-Internal", "true")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRateLimitForwardedHeaders(t *testing.T) {
+	limiter := limit.NewKeyedLimiter(limit.WindowLimitTemplate(60), 2)
+	defer limiter.Close()
+
+	handler := httpx.RateLimit(limiter,
+		httpx.WithAllowList("10.0.0.0/8"),
+		httpx.WithTrustedProxies("192.168.0.0/24"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(remoteAddr string, forwardedFor ...string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		for _, v := range forwardedFor {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		r.Header.Set("X-Real-IP", "10.9.9.9")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// the headers of an untrusted caller neither bypass the limit nor change its key.
+	assert.Equal(t, http.StatusOK, serve("1.2.3.4:1000", "10.1.2.3"))
+	assert.Equal(t, http.StatusOK, serve("1.2.3.4:1000", "5.6.7.8"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("1.2.3.4:1000", "10.1.2.3"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("1.2.3.4:1000", "9.9.9.9"))
+
+	// behind the trusted proxies, the caller is the first untrusted hop from the
+	// right, the spoofed entries on its left are ignored.
+	for _, spoofed := range []string{"10.1.2.3", "1.1.1.1", "2.2.2.2"} {
+		code := serve("192.168.0.1:1000", spoofed+", 5.6.7.8, 192.168.0.2")
+		if spoofed == "2.2.2.2" {
+			assert.Equal(t, http.StatusTooManyRequests, code)
+		} else {
+			assert.Equal(t, http.StatusOK, code)
+		}
+	}
+	// the headers are a single list.
+	assert.Equal(t, http.StatusTooManyRequests, serve("192.168.0.1:1000", "10.1.2.3", "5.6.7.8,192.168.0.2"))
+	assert.Equal(t, http.StatusOK, serve("192.168.0.1:1000", "10.1.2.3, 6.7.8.9"))
+
+	// the internal callers behind the proxies bypass the limit.
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, serve("192.168.0.1:1000", "5.6.7.8, 10.1.2.3, 192.168.0.2"))
+	}
+
+	// a malformed hop stops the walk at the last trusted proxy.
+	assert.Equal(t, http.StatusOK, serve("192.168.0.1:1000", "7.7.7.7, bad, 192.168.0.2"))
+	assert.Equal(t, http.StatusOK, serve("192.168.0.1:1000", "8.8.8.8, bad, 192.168.0.2"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("192.168.0.1:1000", "9.9.9.9, bad, 192.168.0.2"))
+}
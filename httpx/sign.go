@@ -0,0 +1,260 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 01:38:44
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 01:38:44
+ * @Description:
+ */
+package httpx
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/cnzf1/gocore/collection/mapx"
+)
+
+const (
+	HeaderKeyID     = "X-Key-Id"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+
+	defaultSignWindow  = 5 * time.Minute
+	defaultMaxBodySize = 4 << 20
+)
+
+var (
+	ErrSignatureMissing = errors.New("request signature is missing")
+	ErrSignatureInvalid = errors.New("request signature is invalid")
+	ErrUnknownKey       = errors.New("request is signed with an unknown key")
+	ErrTimestamp        = errors.New("request timestamp is out of the window")
+	ErrReplay           = errors.New("request nonce was already used")
+	ErrBodyTooLarge     = errors.New("request body is too large")
+)
+
+type (
+	// SignerLookup returns the signer of keyID.
+	SignerLookup func(keyID string) (codec.Signer, bool)
+
+	// RequestVerifier verifies the requests signed by SignRequest, the requests
+	// out of the timestamp window or reusing a nonce are rejected.
+	RequestVerifier struct {
+		lookup      SignerLookup
+		window      time.Duration
+		maxBodySize int64
+		nonces      *mapx.ExpiredMap
+		lock        sync.Mutex
+		now         func() time.Time
+	}
+
+	// VerifierOption customizes a RequestVerifier.
+	VerifierOption func(*RequestVerifier)
+)
+
+// WithSign signs the request with SignRequest, it must be the last option
+// since the query and the body are signed. The request isn't sent if it fails.
+func WithSign(keyID string, signer codec.Signer) httpxOption {
+	return func(r *http.Request) {
+		if err := SignRequest(r, keyID, signer); err != nil {
+			setOptionError(r, err)
+		}
+	}
+}
+
+// SignRequest signs the canonical request of r with signer and SHA256, and sets
+// the key id, timestamp, nonce and signature headers. The canonical request is
+// made of the method, the path, the sorted query, the hex SHA256 of the body,
+// the timestamp and the nonce, separated by new lines.
+func SignRequest(r *http.Request, keyID string, signer codec.Signer) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	r.Header.Set(HeaderKeyID, keyID)
+	r.Header.Set(HeaderTimestamp, timestamp)
+	r.Header.Set(HeaderNonce, hex.EncodeToString(nonce))
+
+	sign, err := signer.Sign(canonicalRequest(r, body), crypto.SHA256)
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set(HeaderSignature, base64.StdEncoding.EncodeToString(sign))
+	return nil
+}
+
+// WithSignWindow sets the largest tolerated difference between the timestamp of
+// a request and the local time, 5 minutes by default.
+func WithSignWindow(window time.Duration) VerifierOption {
+	return func(v *RequestVerifier) {
+		v.window = window
+	}
+}
+
+// WithMaxBodySize sets the largest body read to verify a request, 4MB by default.
+func WithMaxBodySize(size int64) VerifierOption {
+	return func(v *RequestVerifier) {
+		v.maxBodySize = size
+	}
+}
+
+// WithVerifierClock sets the function returning the current time, it's meant for tests.
+func WithVerifierClock(now func() time.Time) VerifierOption {
+	return func(v *RequestVerifier) {
+		v.now = now
+	}
+}
+
+// NewRequestVerifier returns a RequestVerifier using the signers returned by lookup.
+// Close must be called to release the nonce cache.
+func NewRequestVerifier(lookup SignerLookup, opts ...VerifierOption) *RequestVerifier {
+	v := &RequestVerifier{
+		lookup:      lookup,
+		window:      defaultSignWindow,
+		maxBodySize: defaultMaxBodySize,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	v.nonces = mapx.NewExpiredMap(mapx.WithTick(v.window))
+	return v
+}
+
+// Verify verifies the signature of r, the body of r can still be read afterwards.
+// The bodies larger than the max body size are rejected with ErrBodyTooLarge.
+func (v *RequestVerifier) Verify(r *http.Request) error {
+	keyID := r.Header.Get(HeaderKeyID)
+	timestamp := r.Header.Get(HeaderTimestamp)
+	nonce := r.Header.Get(HeaderNonce)
+	signature := r.Header.Get(HeaderSignature)
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return ErrSignatureMissing
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrTimestamp
+	}
+	if d := v.now().Sub(time.Unix(ts, 0)); d > v.window || d < -v.window {
+		return ErrTimestamp
+	}
+
+	signer, ok := v.lookup(keyID)
+	if !ok {
+		return ErrUnknownKey
+	}
+
+	sign, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+
+	if r.ContentLength > v.maxBodySize {
+		return ErrBodyTooLarge
+	}
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = http.MaxBytesReader(nil, r.Body, v.maxBodySize)
+	}
+	body, err := readBody(r)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return ErrBodyTooLarge
+		}
+		return err
+	}
+	if !signer.Verify(canonicalRequest(r, body), sign, crypto.SHA256) {
+		return ErrSignatureInvalid
+	}
+
+	// the nonce is kept as long as its timestamp is in the window.
+	key := keyID + ":" + nonce
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if _, ok := v.nonces.Get(key); ok {
+		return ErrReplay
+	}
+	v.nonces.Set(key, ts, 2*v.window)
+	return nil
+}
+
+// Middleware rejects the requests failing Verify with 401 Unauthorized, or with
+// 413 Request Entity Too Large if the body exceeds the max body size.
+func (v *RequestVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Verify(r); err != nil {
+			status := http.StatusUnauthorized
+			if err == ErrBodyTooLarge {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Close stops the nonce cache.
+func (v *RequestVerifier) Close() {
+	v.nonces.Close()
+}
+
+func canonicalRequest(r *http.Request, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteByte('\n')
+	b.WriteString(r.URL.EscapedPath())
+	b.WriteByte('\n')
+	b.WriteString(r.URL.Query().Encode())
+	b.WriteByte('\n')
+	b.WriteString(hex.EncodeToString(bodyHash[:]))
+	b.WriteByte('\n')
+	b.WriteString(r.Header.Get(HeaderTimestamp))
+	b.WriteByte('\n')
+	b.WriteString(r.Header.Get(HeaderNonce))
+	return []byte(b.String())
+}
+
+// readBody reads the body of r, and puts back a reader of the same content.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
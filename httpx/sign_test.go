@@ -0,0 +1,140 @@
+package httpx_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cnzf1/gocore/codec"
+	"github.com/cnzf1/gocore/httpx"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSignVerifier(opts ...httpx.VerifierOption) *httpx.RequestVerifier {
+	signer := codec.NewHmac([]byte("secret"))
+	return httpx.NewRequestVerifier(func(keyID string) (codec.Signer, bool) {
+		return signer, keyID == "app"
+	}, opts...)
+}
+
+func newSignedRequest(t *testing.T, body string) *http.Request {
+	r, err := http.NewRequest(http.MethodPost, "http://localhost/orders?b=2&a=1", bytes.NewBufferString(body))
+	assert.Nil(t, err)
+	assert.Nil(t, httpx.SignRequest(r, "app", codec.NewHmac([]byte("secret"))))
+	return r
+}
+
+func TestSignRequest(t *testing.T) {
+	v := newSignVerifier()
+	defer v.Close()
+
+	r := newSignedRequest(t, `{"id":1}`)
+	assert.Nil(t, v.Verify(r))
+	body, err := ioutil.ReadAll(r.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"id":1}`, string(body))
+
+	// replayed.
+	r.Body = ioutil.NopCloser(bytes.NewBufferString(`{"id":1}`))
+	assert.Equal(t, httpx.ErrReplay, v.Verify(r))
+
+	r = newSignedRequest(t, `{"id":1}`)
+	r.Body = ioutil.NopCloser(bytes.NewBufferString(`{"id":2}`))
+	assert.Equal(t, httpx.ErrSignatureInvalid, v.Verify(r))
+
+	r = newSignedRequest(t, "")
+	r.URL.RawQuery = "a=1&b=3"
+	assert.Equal(t, httpx.ErrSignatureInvalid, v.Verify(r))
+
+	r = newSignedRequest(t, "")
+	r.Header.Set(httpx.HeaderKeyID, "other")
+	assert.Equal(t, httpx.ErrUnknownKey, v.Verify(r))
+
+	r = newSignedRequest(t, "")
+	r.Header.Del(httpx.HeaderSignature)
+	assert.Equal(t, httpx.ErrSignatureMissing, v.Verify(r))
+}
+
+func TestSignRequestWindow(t *testing.T) {
+	v := newSignVerifier(httpx.WithSignWindow(time.Minute), httpx.WithVerifierClock(func() time.Time {
+		return time.Now().Add(2 * time.Minute)
+	}))
+	defer v.Close()
+
+	assert.Equal(t, httpx.ErrTimestamp, v.Verify(newSignedRequest(t, "")))
+}
+
+func TestSignMaxBodySize(t *testing.T) {
+	v := newSignVerifier(httpx.WithMaxBodySize(8))
+	defer v.Close()
+
+	assert.Nil(t, v.Verify(newSignedRequest(t, `{"id":1}`)))
+	assert.Equal(t, httpx.ErrBodyTooLarge, v.Verify(newSignedRequest(t, `{"id":10}`)))
+
+	// the length is unknown, the body is cut at the max body size.
+	r := newSignedRequest(t, `{"id":10}`)
+	r.ContentLength = -1
+	r.Body = ioutil.NopCloser(bytes.NewBufferString(`{"id":10}`))
+	assert.Equal(t, httpx.ErrBodyTooLarge, v.Verify(r))
+}
+
+func TestWithSignError(t *testing.T) {
+	var called bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	// Ed25519 can't sign a SHA256 digest, the request must not be sent unsigned.
+	edObj, err := codec.CreateEd25519()
+	assert.Nil(t, err)
+	_, err = httpx.Client().Post(srv.URL+"/orders", map[string]int{"id": 1}, httpx.WithSign("app", edObj))
+	assert.Equal(t, codec.ErrEd25519Hash, err)
+	assert.False(t, called)
+}
+
+func TestCustomOption(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Custom")))
+	}))
+	defer srv.Close()
+
+	// the options are plain functions of the request.
+	result, err := httpx.Client().Post(srv.URL, nil, func(r *http.Request) {
+		r.Header.Set("X-Custom", "custom")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "custom", string(result))
+}
+
+func TestSignMiddleware(t *testing.T) {
+	v := newSignVerifier()
+	defer v.Close()
+
+	srv := httptest.NewServer(v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})))
+	defer srv.Close()
+
+	result, err := httpx.Client().Post(srv.URL+"/orders", map[string]int{"id": 1},
+		httpx.WithParams(map[string]string{"a": "1"}), httpx.WithSign("app", codec.NewHmac([]byte("secret"))))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"id":1}`, string(result))
+
+	resp, err := http.Post(srv.URL+"/orders", "application/json", bytes.NewBufferString(`{"id":1}`))
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	r := newSignedRequest(t, string(make([]byte, 5<<20)))
+	r.URL, _ = url.Parse(srv.URL + "/orders?b=2&a=1")
+	resp, err = http.DefaultClient.Do(r)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
@@ -0,0 +1,172 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-17 20:45:10
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-17 20:45:10
+ * @Description: 缓存快照的二进制格式
+ */
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Version is the version of the format written by Writer.
+const Version = 1
+
+const (
+	recordEnd   byte = 0
+	recordEntry byte = 1
+
+	// maxFieldSize protects Reader from the lengths of corrupted data.
+	maxFieldSize = 1 << 30
+	// readChunkSize bounds the memory allocated ahead of the data read.
+	readChunkSize = 64 << 10
+)
+
+var (
+	magic = []byte("GCSNAP")
+
+	// ErrBadFormat indicates that the data is not a snapshot or is truncated.
+	ErrBadFormat = errors.New("snapshot: bad format")
+	// ErrVersion indicates that the snapshot was written by an unsupported version.
+	ErrVersion = errors.New("snapshot: unsupported version")
+)
+
+type (
+	// Entry is a cache entry, the entries are written from the least to the
+	// most recently used, so that replaying them restores the order.
+	Entry struct {
+		Key   []byte
+		Value []byte
+		// Expire is the expiration time in unix nanoseconds, 0 means never expire.
+		Expire int64
+		Cost   int64
+	}
+
+	// Writer writes a snapshot, the header is written by NewWriter and the
+	// trailer by Close.
+	Writer struct {
+		w   *bufio.Writer
+		buf [binary.MaxVarintLen64]byte
+	}
+
+	// Reader reads a snapshot written by Writer.
+	Reader struct {
+		r *bufio.Reader
+	}
+)
+
+// NewWriter writes the header to w and returns a Writer.
+func NewWriter(w io.Writer) (*Writer, error) {
+	sw := &Writer{
+		w: bufio.NewWriter(w),
+	}
+
+	sw.w.Write(magic)
+	if err := sw.w.WriteByte(Version); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+// Write writes e.
+func (w *Writer) Write(e Entry) error {
+	w.w.WriteByte(recordEntry)
+	w.writeBytes(e.Key)
+	w.writeBytes(e.Value)
+	w.writeVarint(e.Expire)
+	return w.writeVarint(e.Cost)
+}
+
+// Close writes the trailer and flushes the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.w.WriteByte(recordEnd); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+func (w *Writer) writeBytes(b []byte) error {
+	n := binary.PutUvarint(w.buf[:], uint64(len(b)))
+	w.w.Write(w.buf[:n])
+	_, err := w.w.Write(b)
+	return err
+}
+
+func (w *Writer) writeVarint(v int64) error {
+	n := binary.PutVarint(w.buf[:], v)
+	_, err := w.w.Write(w.buf[:n])
+	return err
+}
+
+// NewReader reads and checks the header from r, and returns a Reader.
+func NewReader(r io.Reader) (*Reader, error) {
+	sr := &Reader{
+		r: bufio.NewReader(r),
+	}
+
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(sr.r, header); err != nil {
+		return nil, ErrBadFormat
+	}
+	if !bytes.Equal(header[:len(magic)], magic) {
+		return nil, ErrBadFormat
+	}
+	if header[len(magic)] != Version {
+		return nil, ErrVersion
+	}
+	return sr, nil
+}
+
+// Next returns the next entry, or io.EOF after the last one.
+func (r *Reader) Next() (e Entry, err error) {
+	kind, err := r.r.ReadByte()
+	if err != nil {
+		return e, ErrBadFormat
+	}
+	switch kind {
+	case recordEnd:
+		return e, io.EOF
+	case recordEntry:
+	default:
+		return e, ErrBadFormat
+	}
+
+	if e.Key, err = r.readBytes(); err != nil {
+		return
+	}
+	if e.Value, err = r.readBytes(); err != nil {
+		return
+	}
+	if e.Expire, err = binary.ReadVarint(r.r); err != nil {
+		return e, ErrBadFormat
+	}
+	if e.Cost, err = binary.ReadVarint(r.r); err != nil {
+		return e, ErrBadFormat
+	}
+	return e, nil
+}
+
+func (r *Reader) readBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil || n > maxFieldSize {
+		return nil, ErrBadFormat
+	}
+
+	// the buffer grows with the data actually read, not with the length read.
+	var buf bytes.Buffer
+	if n < readChunkSize {
+		buf.Grow(int(n))
+	} else {
+		buf.Grow(readChunkSize)
+	}
+	if _, err := io.CopyN(&buf, r.r, int64(n)); err != nil {
+		return nil, ErrBadFormat
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,190 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 05:21:37
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 05:21:37
+ * @Description: 自适应并发限流
+ */
+package limit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	// LimitAlgorithm computes the concurrency limit from the samples of the
+	// requests. It's called by AdaptiveLimiter under its lock, so it needs
+	// not to be concurrent safe.
+	LimitAlgorithm interface {
+		// Limit returns the current limit.
+		Limit() int
+		// Update adds the sample of a request which took rtt with inflight
+		// requests in flight, dropped if it was rejected or timed out by the
+		// backend, and returns the new limit.
+		Update(rtt time.Duration, inflight int, dropped bool) int
+	}
+
+	// AdaptiveLimiter limits the requests in flight to a limit adjusted by a
+	// LimitAlgorithm, as the latency and the drops of the backend change.
+	// It's concurrent safe.
+	AdaptiveLimiter struct {
+		mu       sync.Mutex
+		algo     LimitAlgorithm
+		limit    int
+		inflight int
+		// the callers waiting for a slot, in the order of arrival.
+		waiters []chan struct{}
+		now     func() time.Time
+	}
+
+	// AdaptiveLimiterOption customizes an AdaptiveLimiter.
+	AdaptiveLimiterOption func(*AdaptiveLimiter)
+
+	// Token is a slot of an AdaptiveLimiter, it must be released by exactly
+	// one of OnSuccess, OnDropped and OnIgnore once the request is done.
+	Token struct {
+		limiter  *AdaptiveLimiter
+		start    time.Time
+		inflight int
+		once     sync.Once
+	}
+)
+
+// WithAdaptiveLimiterClock sets the function returning the current time, it's meant for tests.
+func WithAdaptiveLimiterClock(now func() time.Time) AdaptiveLimiterOption {
+	return func(l *AdaptiveLimiter) {
+		l.now = now
+	}
+}
+
+// NewAdaptiveLimiter returns an AdaptiveLimiter adjusting its limit with algo.
+func NewAdaptiveLimiter(algo LimitAlgorithm, opts ...AdaptiveLimiterOption) *AdaptiveLimiter {
+	l := &AdaptiveLimiter{
+		algo:  algo,
+		limit: algo.Limit(),
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Limit returns the current limit.
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// Inflight returns the number of the tokens not released yet.
+func (l *AdaptiveLimiter) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+// TryAcquire returns a token if the limit isn't reached, false otherwise.
+func (l *AdaptiveLimiter) TryAcquire() (*Token, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inflight >= l.limit || len(l.waiters) > 0 {
+		return nil, false
+	}
+	l.inflight++
+	return l.newToken(), true
+}
+
+// Acquire blocks until a token is available or ctx is done, in which case it
+// returns the error of ctx.
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) (*Token, error) {
+	l.mu.Lock()
+	if l.inflight < l.limit && len(l.waiters) == 0 {
+		l.inflight++
+		t := l.newToken()
+		l.mu.Unlock()
+		return t, nil
+	}
+
+	ready := make(chan struct{}, 1)
+	l.waiters = append(l.waiters, ready)
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.newToken(), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		for i, w := range l.waiters {
+			if w == ready {
+				l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+				return nil, ctx.Err()
+			}
+		}
+		// the slot was handed over meanwhile, pass it on.
+		l.inflight--
+		l.wake()
+		return nil, ctx.Err()
+	}
+}
+
+// newToken must be called with the lock held, once the slot is taken.
+func (l *AdaptiveLimiter) newToken() *Token {
+	return &Token{
+		limiter:  l,
+		start:    l.now(),
+		inflight: l.inflight,
+	}
+}
+
+// wake hands the free slots over to the waiters, it must be called with the lock held.
+func (l *AdaptiveLimiter) wake() {
+	for l.inflight < l.limit && len(l.waiters) > 0 {
+		ready := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.inflight++
+		ready <- struct{}{}
+	}
+}
+
+func (l *AdaptiveLimiter) release(t *Token, sample, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+	if sample {
+		l.limit = l.algo.Update(l.now().Sub(t.start), t.inflight, dropped)
+	}
+	l.wake()
+}
+
+// OnSuccess releases the token of a request which succeeded, its latency is
+// sampled to adjust the limit.
+func (t *Token) OnSuccess() {
+	t.once.Do(func() {
+		t.limiter.release(t, true, false)
+	})
+}
+
+// OnDropped releases the token of a request which was rejected or timed out by
+// the backend, the limit is lowered.
+func (t *Token) OnDropped() {
+	t.once.Do(func() {
+		t.limiter.release(t, true, true)
+	})
+}
+
+// OnIgnore releases the token of a request which failed for a reason unrelated
+// to the load, such as a bad request, the limit is kept.
+func (t *Token) OnIgnore() {
+	t.once.Do(func() {
+		t.limiter.release(t, false, false)
+	})
+}
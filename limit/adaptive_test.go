@@ -0,0 +1,116 @@
+package limit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAIMDLimit(t *testing.T) {
+	a := NewAIMDLimit(WithInitialLimit(10), WithMaxLimit(11), WithTimeout(time.Second))
+	assert.Equal(t, 10, a.Limit())
+
+	// unused limit isn't raised.
+	assert.Equal(t, 10, a.Update(time.Millisecond, 2, false))
+	assert.Equal(t, 11, a.Update(time.Millisecond, 5, false))
+	assert.Equal(t, 11, a.Update(time.Millisecond, 11, false))
+
+	assert.Equal(t, 9, a.Update(time.Millisecond, 11, true))
+	assert.Equal(t, 8, a.Update(2*time.Second, 9, false))
+
+	for i := 0; i < 100; i++ {
+		a.Update(time.Millisecond, 1, true)
+	}
+	assert.Equal(t, 1, a.Limit())
+}
+
+func TestVegasLimit(t *testing.T) {
+	v := NewVegasLimit(WithInitialLimit(10))
+
+	// the first sample is the latency without load.
+	assert.Equal(t, 10, v.Update(10*time.Millisecond, 10, false))
+	// no queue, the limit grows fast.
+	assert.Equal(t, 16, v.Update(10*time.Millisecond, 10, false))
+	// a long queue, the limit drops.
+	assert.Equal(t, 14, v.Update(50*time.Millisecond, 16, false))
+	assert.Equal(t, 13, v.Update(10*time.Millisecond, 14, true))
+	// unused limit isn't raised.
+	assert.Equal(t, 13, v.Update(10*time.Millisecond, 1, false))
+}
+
+func TestGradient2Limit(t *testing.T) {
+	g := NewGradient2Limit(WithInitialLimit(20))
+
+	// a steady latency raises the limit by its queue.
+	for i := 0; i < 20; i++ {
+		g.Update(10*time.Millisecond, g.Limit(), false)
+	}
+	steady := g.Limit()
+	assert.Greater(t, steady, 20)
+
+	// the latency rising over its trend lowers the limit.
+	for i := 0; i < 5; i++ {
+		g.Update(100*time.Millisecond, g.Limit(), false)
+	}
+	assert.Less(t, g.Limit(), steady)
+
+	limit := g.Limit()
+	assert.Less(t, g.Update(10*time.Millisecond, limit, true), limit)
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewAdaptiveLimiter(NewAIMDLimit(WithInitialLimit(2), WithTimeout(time.Second)),
+		WithAdaptiveLimiterClock(func() time.Time { return now }))
+
+	t1, ok := l.TryAcquire()
+	assert.True(t, ok)
+	t2, ok := l.TryAcquire()
+	assert.True(t, ok)
+	_, ok = l.TryAcquire()
+	assert.False(t, ok)
+	assert.Equal(t, 2, l.Inflight())
+
+	// the waiter takes the slot released.
+	acquired := make(chan *Token)
+	go func() {
+		tok, err := l.Acquire(context.Background())
+		assert.Nil(t, err)
+		acquired <- tok
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	t1.OnSuccess()
+	assert.Equal(t, 3, l.Limit())
+	t3 := <-acquired
+	assert.Equal(t, 2, l.Inflight())
+
+	// the tokens are released once.
+	t1.OnDropped()
+	assert.Equal(t, 3, l.Limit())
+
+	now = now.Add(2 * time.Second)
+	t2.OnSuccess()
+	assert.Equal(t, 2, l.Limit())
+	t3.OnIgnore()
+	assert.Equal(t, 2, l.Limit())
+	assert.Equal(t, 0, l.Inflight())
+}
+
+func TestAdaptiveLimiterCancel(t *testing.T) {
+	l := NewAdaptiveLimiter(NewAIMDLimit(WithInitialLimit(1)))
+	tok, err := l.Acquire(context.Background())
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.Acquire(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	tok.OnIgnore()
+	assert.Equal(t, 0, l.Inflight())
+	_, ok := l.TryAcquire()
+	assert.True(t, ok)
+}
@@ -0,0 +1,305 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 05:21:37
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 05:21:37
+ * @Description: 自适应并发限流的算法
+ */
+package limit
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	defaultInitialLimit = 20
+	defaultMinLimit     = 1
+	defaultMaxLimit     = 200
+)
+
+type (
+	// AlgorithmOption customizes a LimitAlgorithm.
+	AlgorithmOption func(*algorithmConfig)
+
+	algorithmConfig struct {
+		initialLimit int
+		minLimit     int
+		maxLimit     int
+		backoff      float64
+		timeout      time.Duration
+		smoothing    float64
+		tolerance    float64
+		longWindow   int
+		probeEvery   int
+	}
+
+	// AIMDLimit raises the limit by one on each success while the limit is in
+	// use, and multiplies it by the backoff ratio on each drop or timeout.
+	AIMDLimit struct {
+		cfg   algorithmConfig
+		limit int
+	}
+
+	// VegasLimit estimates the requests queued in the backend from the
+	// increase of the latency over the latency without load, and keeps the
+	// queue between alpha and beta, which grow with the log of the limit.
+	VegasLimit struct {
+		cfg       algorithmConfig
+		limit     float64
+		rttNoLoad time.Duration
+		samples   int
+	}
+
+	// Gradient2Limit scales the limit by the ratio of the long term average
+	// latency to the latest one, plus a queue of the square root of the limit,
+	// so that the limit drops as soon as the latency rises above its trend.
+	Gradient2Limit struct {
+		cfg     algorithmConfig
+		limit   float64
+		longRtt *ema
+	}
+
+	// ema is an exponential moving average, starting from the plain average
+	// of its first samples.
+	ema struct {
+		window  int
+		warmup  int
+		count   int
+		sum     float64
+		average float64
+	}
+)
+
+// WithInitialLimit sets the limit before any sample, 20 by default.
+func WithInitialLimit(limit int) AlgorithmOption {
+	return func(c *algorithmConfig) {
+		c.initialLimit = limit
+	}
+}
+
+// WithMinLimit sets the lowest limit, 1 by default.
+func WithMinLimit(limit int) AlgorithmOption {
+	return func(c *algorithmConfig) {
+		c.minLimit = limit
+	}
+}
+
+// WithMaxLimit sets the highest limit, 200 by default.
+func WithMaxLimit(limit int) AlgorithmOption {
+	return func(c *algorithmConfig) {
+		c.maxLimit = limit
+	}
+}
+
+// WithBackoffRatio sets the ratio applied to the limit of AIMDLimit on a drop, 0.9 by default.
+func WithBackoffRatio(ratio float64) AlgorithmOption {
+	return func(c *algorithmConfig) {
+		c.backoff = ratio
+	}
+}
+
+// WithTimeout sets the latency over which AIMDLimit takes a request as dropped, 5s by default.
+func WithTimeout(timeout time.Duration) AlgorithmOption {
+	return func(c *algorithmConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithSmoothing sets the weight of a new limit of VegasLimit and Gradient2Limit
+// against the current one, 1 for Vegas and 0.2 for Gradient2 by default.
+func WithSmoothing(smoothing float64) AlgorithmOption {
+	return func(c *algorithmConfig) {
+		c.smoothing = smoothing
+	}
+}
+
+// WithRttTolerance sets how much the latency of Gradient2Limit may rise above
+// its long term average before the limit drops, 1.5 by default.
+func WithRttTolerance(tolerance float64) AlgorithmOption {
+	return func(c *algorithmConfig) {
+		c.tolerance = tolerance
+	}
+}
+
+// WithLongWindow sets the samples averaged by the long term latency of Gradient2Limit, 600 by default.
+func WithLongWindow(window int) AlgorithmOption {
+	return func(c *algorithmConfig) {
+		c.longWindow = window
+	}
+}
+
+// WithProbeEvery sets the samples after which VegasLimit probes again the latency
+// without load, 1000 by default. 0 disables the probes.
+func WithProbeEvery(samples int) AlgorithmOption {
+	return func(c *algorithmConfig) {
+		c.probeEvery = samples
+	}
+}
+
+func newAlgorithmConfig(smoothing float64, opts []AlgorithmOption) algorithmConfig {
+	c := algorithmConfig{
+		initialLimit: defaultInitialLimit,
+		minLimit:     defaultMinLimit,
+		maxLimit:     defaultMaxLimit,
+		backoff:      0.9,
+		timeout:      5 * time.Second,
+		smoothing:    smoothing,
+		tolerance:    1.5,
+		longWindow:   600,
+		probeEvery:   1000,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	// a limit below 1 would block all the requests for good.
+	if c.minLimit < 1 {
+		c.minLimit = 1
+	}
+	return c
+}
+
+func (c algorithmConfig) clamp(limit float64) float64 {
+	return math.Max(float64(c.minLimit), math.Min(float64(c.maxLimit), limit))
+}
+
+// NewAIMDLimit returns an AIMDLimit.
+func NewAIMDLimit(opts ...AlgorithmOption) *AIMDLimit {
+	cfg := newAlgorithmConfig(1, opts)
+	return &AIMDLimit{
+		cfg:   cfg,
+		limit: int(cfg.clamp(float64(cfg.initialLimit))),
+	}
+}
+
+func (a *AIMDLimit) Limit() int {
+	return a.limit
+}
+
+func (a *AIMDLimit) Update(rtt time.Duration, inflight int, dropped bool) int {
+	if dropped || rtt > a.cfg.timeout {
+		a.limit = int(a.cfg.clamp(float64(a.limit) * a.cfg.backoff))
+	} else if inflight*2 >= a.limit {
+		// the limit isn't raised while most of it is left unused.
+		a.limit = int(a.cfg.clamp(float64(a.limit + 1)))
+	}
+	return a.limit
+}
+
+// NewVegasLimit returns a VegasLimit.
+func NewVegasLimit(opts ...AlgorithmOption) *VegasLimit {
+	cfg := newAlgorithmConfig(1, opts)
+	return &VegasLimit{
+		cfg:   cfg,
+		limit: cfg.clamp(float64(cfg.initialLimit)),
+	}
+}
+
+func (v *VegasLimit) Limit() int {
+	return int(v.limit)
+}
+
+func (v *VegasLimit) Update(rtt time.Duration, inflight int, dropped bool) int {
+	v.samples++
+	if v.cfg.probeEvery > 0 && v.samples >= v.cfg.probeEvery {
+		// the latency without load may have changed, such as the backend moved.
+		v.samples = 0
+		v.rttNoLoad = 0
+	}
+
+	if rtt <= 0 {
+		return v.Limit()
+	}
+	if v.rttNoLoad == 0 || rtt < v.rttNoLoad {
+		v.rttNoLoad = rtt
+		return v.Limit()
+	}
+
+	logLimit := math.Max(1, math.Log10(v.limit))
+	limit := v.limit
+	switch queue := math.Ceil(v.limit * (1 - float64(v.rttNoLoad)/float64(rtt))); {
+	case dropped:
+		limit -= logLimit
+	case float64(inflight)*2 < v.limit:
+		// the limit isn't raised while most of it is left unused.
+		return v.Limit()
+	case queue <= logLimit:
+		limit += 6 * logLimit
+	case queue < 3*logLimit:
+		limit += logLimit
+	case queue > 6*logLimit:
+		limit -= logLimit
+	default:
+		return v.Limit()
+	}
+
+	limit = v.cfg.clamp(limit)
+	v.limit = (1-v.cfg.smoothing)*v.limit + v.cfg.smoothing*limit
+	return v.Limit()
+}
+
+// NewGradient2Limit returns a Gradient2Limit.
+func NewGradient2Limit(opts ...AlgorithmOption) *Gradient2Limit {
+	cfg := newAlgorithmConfig(0.2, opts)
+	return &Gradient2Limit{
+		cfg:     cfg,
+		limit:   cfg.clamp(float64(cfg.initialLimit)),
+		longRtt: newEma(cfg.longWindow, 10),
+	}
+}
+
+func (g *Gradient2Limit) Limit() int {
+	return int(g.limit)
+}
+
+func (g *Gradient2Limit) Update(rtt time.Duration, inflight int, dropped bool) int {
+	if rtt <= 0 {
+		return g.Limit()
+	}
+
+	shortRtt := float64(rtt)
+	longRtt := g.longRtt.add(shortRtt)
+
+	// the long term latency recovers faster once the load went down.
+	if longRtt/shortRtt > 2 {
+		longRtt = g.longRtt.update(longRtt * 0.95)
+	}
+
+	// the limit isn't raised while most of it is left unused.
+	if !dropped && float64(inflight) < g.limit/2 {
+		return g.Limit()
+	}
+
+	gradient := math.Max(0.5, math.Min(1, g.cfg.tolerance*longRtt/shortRtt))
+	if dropped {
+		gradient = 0.5
+	}
+	limit := g.cfg.clamp(g.limit*gradient + math.Sqrt(g.limit))
+	g.limit = g.cfg.clamp((1-g.cfg.smoothing)*g.limit + g.cfg.smoothing*limit)
+	return g.Limit()
+}
+
+func newEma(window, warmup int) *ema {
+	return &ema{
+		window: window,
+		warmup: warmup,
+	}
+}
+
+func (e *ema) add(sample float64) float64 {
+	if e.count < e.warmup {
+		e.count++
+		e.sum += sample
+		e.average = e.sum / float64(e.count)
+	} else {
+		factor := 2 / float64(e.window+1)
+		e.average = e.average*(1-factor) + sample*factor
+	}
+	return e.average
+}
+
+func (e *ema) update(average float64) float64 {
+	e.average = average
+	return e.average
+}
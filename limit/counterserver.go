@@ -0,0 +1,385 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 04:56:14
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 04:56:14
+ * @Description: 分布式滑动窗口的计数服务
+ */
+package limit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cnzf1/gocore/thread"
+)
+
+const (
+	// the windows kept for a key, the current and the previous ones.
+	keptWindows = 2
+
+	defaultMaxRequestSize = 1 << 20
+	defaultConnTimeout    = 5 * time.Minute
+	// bounds a round trip of the default HTTPCounterClient, not to block the
+	// synchronizer on a hung server.
+	defaultCounterClientTimeout = 5 * time.Second
+)
+
+var ErrCounterServer = errors.New("limit: counter server failed")
+
+type (
+	// CounterServer counts the events of the windows across several processes,
+	// it's served over HTTP by ServeHTTP, or over TCP by Serve. It's concurrent safe.
+	CounterServer struct {
+		lock           sync.Mutex
+		keys           map[string]*counterKey
+		idle           time.Duration
+		maxRequestSize int64
+		connTimeout    time.Duration
+		lastSweep      time.Time
+		now            func() time.Time
+	}
+
+	// CounterServerOption customizes a CounterServer.
+	CounterServerOption func(*CounterServer)
+
+	counterKey struct {
+		windows []counterWindow
+		access  time.Time
+	}
+
+	counterWindow struct {
+		start int64
+		count int64
+	}
+
+	counterResponse struct {
+		Counts []int64 `json:"counts"`
+		Error  string  `json:"error,omitempty"`
+	}
+
+	// HTTPCounterClient is a CounterClient posting the deltas to a CounterServer
+	// served over HTTP.
+	HTTPCounterClient struct {
+		url    string
+		client *http.Client
+	}
+
+	// TCPCounterClient is a CounterClient sending the deltas to a CounterServer
+	// served over TCP, on a connection dialed again once broken. It's concurrent safe.
+	TCPCounterClient struct {
+		addr    string
+		timeout time.Duration
+		lock    sync.Mutex
+		conn    net.Conn
+		reader  *bufio.Reader
+	}
+)
+
+// WithCounterIdleTimeout sets how long the windows of a key are kept without
+// deltas, 10 minutes by default.
+func WithCounterIdleTimeout(idle time.Duration) CounterServerOption {
+	return func(s *CounterServer) {
+		s.idle = idle
+	}
+}
+
+// WithMaxRequestSize sets the largest request read, over HTTP or TCP, 1MB by default.
+func WithMaxRequestSize(size int64) CounterServerOption {
+	return func(s *CounterServer) {
+		s.maxRequestSize = size
+	}
+}
+
+// WithConnTimeout sets how long a TCP connection waits for a request, and for
+// its counts to be written, 5 minutes by default.
+func WithConnTimeout(timeout time.Duration) CounterServerOption {
+	return func(s *CounterServer) {
+		s.connTimeout = timeout
+	}
+}
+
+// NewCounterServer returns a CounterServer.
+func NewCounterServer(opts ...CounterServerOption) *CounterServer {
+	s := &CounterServer{
+		keys:           make(map[string]*counterKey),
+		idle:           defaultIdleTimeout,
+		maxRequestSize: defaultMaxRequestSize,
+		connTimeout:    defaultConnTimeout,
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add adds the deltas to the counts of their windows and returns the counts,
+// a CounterServer is also the CounterClient of the processes sharing it.
+// Only the two latest windows of a key are kept, the older ones count from 0.
+// The keys without deltas for the idle timeout are dropped.
+func (s *CounterServer) Add(deltas []CounterDelta) ([]int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	counts := make([]int64, len(deltas))
+	for i, d := range deltas {
+		counts[i] = s.add(d, now)
+	}
+	return counts, nil
+}
+
+// Len returns the number of keys having windows.
+func (s *CounterServer) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.keys)
+}
+
+func (s *CounterServer) add(d CounterDelta, now time.Time) int64 {
+	key, ok := s.keys[d.Key]
+	if !ok {
+		key = &counterKey{}
+		s.keys[d.Key] = key
+	}
+	key.access = now
+
+	windows := key.windows
+	for i := range windows {
+		if windows[i].start == d.Start {
+			windows[i].count += d.Delta
+			return windows[i].count
+		}
+	}
+
+	// the windows are sorted by start, the latest last.
+	if len(windows) > 0 && d.Start < windows[0].start {
+		return d.Delta
+	}
+
+	windows = append(windows, counterWindow{start: d.Start, count: d.Delta})
+	for i := len(windows) - 1; i > 0 && windows[i].start < windows[i-1].start; i-- {
+		windows[i], windows[i-1] = windows[i-1], windows[i]
+	}
+	if len(windows) > keptWindows {
+		windows = windows[len(windows)-keptWindows:]
+	}
+	key.windows = windows
+	return d.Delta
+}
+
+// sweep drops the idle keys, at most once per half of the idle timeout.
+func (s *CounterServer) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.idle/2 {
+		return
+	}
+
+	s.lastSweep = now
+	for k, key := range s.keys {
+		if now.Sub(key.access) >= s.idle {
+			delete(s.keys, k)
+		}
+	}
+}
+
+// ServeHTTP adds the JSON deltas posted, and writes the JSON counts.
+func (s *CounterServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var deltas []CounterDelta
+	body := http.MaxBytesReader(w, r.Body, s.maxRequestSize)
+	if err := json.NewDecoder(body).Decode(&deltas); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	counts, _ := s.Add(deltas)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(counterResponse{Counts: counts})
+}
+
+// Serve serves the connections accepted by ln, each request is a JSON array of
+// deltas on a line, answered by a JSON object of counts on a line. A connection
+// is closed once idle for the connection timeout, or after a request larger
+// than the max request size. It returns when ln is closed.
+func (s *CounterServer) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		thread.GoSafe(func() {
+			s.serveConn(conn)
+		})
+	}
+}
+
+// ListenAndServe listens on the TCP address addr and serves the connections.
+func (s *CounterServer) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+func (s *CounterServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	// the max token size is the capacity of the buffer if larger.
+	size := int(s.maxRequestSize)
+	if size > 4096 {
+		size = 4096
+	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, size), int(s.maxRequestSize))
+	enc := json.NewEncoder(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(s.connTimeout))
+		if !scanner.Scan() {
+			// the stream can't be resynchronized after a request too large.
+			if err := scanner.Err(); errors.Is(err, bufio.ErrTooLong) {
+				s.writeConn(conn, enc, counterResponse{Error: err.Error()})
+			}
+			return
+		}
+
+		var deltas []CounterDelta
+		if err := json.Unmarshal(scanner.Bytes(), &deltas); err != nil {
+			s.writeConn(conn, enc, counterResponse{Error: err.Error()})
+			return
+		}
+
+		counts, _ := s.Add(deltas)
+		if err := s.writeConn(conn, enc, counterResponse{Counts: counts}); err != nil {
+			return
+		}
+	}
+}
+
+func (s *CounterServer) writeConn(conn net.Conn, enc *json.Encoder, resp counterResponse) error {
+	conn.SetWriteDeadline(time.Now().Add(s.connTimeout))
+	return enc.Encode(resp)
+}
+
+// NewHTTPCounterClient returns a HTTPCounterClient posting to url with client,
+// or with a client timing out after 5 seconds if nil. A given client should
+// have a timeout as well, a hung server blocks the synchronizer otherwise.
+func NewHTTPCounterClient(url string, client *http.Client) *HTTPCounterClient {
+	if client == nil {
+		client = &http.Client{Timeout: defaultCounterClientTimeout}
+	}
+	return &HTTPCounterClient{
+		url:    url,
+		client: client,
+	}
+}
+
+func (c *HTTPCounterClient) Add(deltas []CounterDelta) ([]int64, error) {
+	body, err := json.Marshal(deltas)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Post(c.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrCounterServer
+	}
+	return decodeCounterResponse(json.NewDecoder(resp.Body))
+}
+
+// NewTCPCounterClient returns a TCPCounterClient of the server at addr, timeout
+// bounds the dial and each round trip.
+func NewTCPCounterClient(addr string, timeout time.Duration) *TCPCounterClient {
+	return &TCPCounterClient{
+		addr:    addr,
+		timeout: timeout,
+	}
+}
+
+func (c *TCPCounterClient) Add(deltas []CounterDelta) ([]int64, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.conn == nil {
+		conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = conn
+		c.reader = bufio.NewReader(conn)
+	}
+
+	counts, err := c.roundTrip(deltas)
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return nil, err
+	}
+	return counts, nil
+}
+
+// Close closes the connection.
+func (c *TCPCounterClient) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *TCPCounterClient) roundTrip(deltas []CounterDelta) ([]int64, error) {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	if err := json.NewEncoder(c.conn).Encode(deltas); err != nil {
+		return nil, err
+	}
+
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	return decodeCounterResponse(json.NewDecoder(bytes.NewReader(line)))
+}
+
+func decodeCounterResponse(dec *json.Decoder) ([]int64, error) {
+	var resp counterResponse
+	if err := dec.Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, ErrCounterServer
+	}
+	return resp.Counts, nil
+}
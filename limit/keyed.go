@@ -0,0 +1,429 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 04:28:51
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 04:28:51
+ * @Description: 按key限流
+ */
+package limit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cnzf1/gocore/collection/mapx"
+	"github.com/cnzf1/gocore/lang"
+	"github.com/cnzf1/gocore/timex"
+)
+
+const defaultIdleTimeout = 10 * time.Minute
+
+type (
+	// KeyLimiter is the limiter of a key of a KeyedLimiter.
+	KeyLimiter interface {
+		// Allow reports whether an event may happen now.
+		Allow() bool
+		// AllowUsage is Allow, and returns the usage counting the event at once.
+		AllowUsage() (bool, Usage)
+		// SetLimit changes the events allowed in a period.
+		SetLimit(limit int64)
+		// Usage returns the events counted against the limit.
+		Usage() Usage
+	}
+
+	// Template creates the limiter of key allowing limit events in a period,
+	// and returns the function to stop it.
+	Template func(key string, limit int64) (KeyLimiter, StopFunc)
+
+	// NewKeyWindow creates the current window of key, such as a SyncWindow
+	// synced under key, and returns the function to stop it.
+	NewKeyWindow func(key string) (Window, StopFunc)
+
+	// Usage is the events counted against the limit of a key.
+	Usage struct {
+		Used  int64
+		Limit int64
+		// Reset is the time until none of the events is counted any longer.
+		Reset time.Duration
+		// RetryAfter is the time until the next event is allowed, 0 if it's allowed now.
+		RetryAfter time.Duration
+	}
+
+	// KeyedLimiter limits the events of each key, such as a user, an IP or an
+	// API key, with a limiter created by a Template on the first event of the
+	// key. The limiters idle for longer than the idle timeout are evicted once
+	// none of their events is counted any longer, so that an eviction never
+	// gives a key its limit back early. It's concurrent safe.
+	KeyedLimiter struct {
+		template  Template
+		limit     int64
+		idle      time.Duration
+		limiters  *mapx.ExpiredMap
+		overrides sync.Map
+		// serializes the creations and the evictions of the limiters and the
+		// changes of the limits, so that a limiter is never created with a stale
+		// limit. The existing limiters are looked up under the read lock.
+		lock   sync.RWMutex
+		closed bool
+	}
+
+	// KeyedLimiterOption customizes a KeyedLimiter.
+	KeyedLimiterOption func(*KeyedLimiter)
+
+	keyedEntry struct {
+		limiter KeyLimiter
+		stop    StopFunc
+		once    sync.Once
+	}
+
+	slidingKeyLimiter struct {
+		*Limiter
+	}
+
+	bucketKeyLimiter struct {
+		*TokenBucket
+		per time.Duration
+	}
+
+	windowKeyLimiter struct {
+		*WindowLimit
+	}
+)
+
+// WithIdleTimeout sets how long a key is kept without events, 10 minutes by default.
+func WithIdleTimeout(idle time.Duration) KeyedLimiterOption {
+	return func(k *KeyedLimiter) {
+		k.idle = idle
+	}
+}
+
+// NewKeyedLimiter returns a KeyedLimiter creating the limiters of the keys with
+// template, allowing limit events in a period unless overridden by SetLimit.
+func NewKeyedLimiter(template Template, limit int64, opts ...KeyedLimiterOption) *KeyedLimiter {
+	k := &KeyedLimiter{
+		template: template,
+		limit:    limit,
+		idle:     defaultIdleTimeout,
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	tick := k.idle / 2
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+	k.limiters = mapx.NewExpiredMap(mapx.WithTick(tick), mapx.WithEvictCallback(k.evict))
+	return k
+}
+
+// Allow reports whether an event of key may happen now.
+func (k *KeyedLimiter) Allow(key string) bool {
+	return k.get(key).limiter.Allow()
+}
+
+// AllowUsage reports whether an event of key may happen now, and returns the
+// usage of key made at once with the decision.
+func (k *KeyedLimiter) AllowUsage(key string) (bool, Usage) {
+	return k.get(key).limiter.AllowUsage()
+}
+
+// SetLimit overrides the limit of key, the limiter of key is kept if it exists.
+func (k *KeyedLimiter) SetLimit(key string, limit int64) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.overrides.Store(key, limit)
+	if e, ok := k.lookup(key); ok {
+		e.limiter.SetLimit(limit)
+	}
+}
+
+// RemoveLimit removes the limit override of key.
+func (k *KeyedLimiter) RemoveLimit(key string) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.overrides.Delete(key)
+	if e, ok := k.lookup(key); ok {
+		e.limiter.SetLimit(k.limit)
+	}
+}
+
+// Limit returns the limit of key.
+func (k *KeyedLimiter) Limit(key string) int64 {
+	if limit, ok := k.overrides.Load(key); ok {
+		return limit.(int64)
+	}
+	return k.limit
+}
+
+// Usage returns the usage of key, false if key has no limiter.
+func (k *KeyedLimiter) Usage(key string) (Usage, bool) {
+	e, ok := k.lookup(key)
+	if !ok {
+		return Usage{}, false
+	}
+	return e.limiter.Usage(), true
+}
+
+// Usages returns the usages of all the keys having a limiter.
+func (k *KeyedLimiter) Usages() map[string]Usage {
+	usages := make(map[string]Usage)
+	k.limiters.Foreach(func(key string, value lang.AnyType) {
+		usages[key] = value.(*keyedEntry).limiter.Usage()
+	})
+	return usages
+}
+
+// Len returns the number of keys having a limiter.
+func (k *KeyedLimiter) Len() int {
+	return int(k.limiters.Size())
+}
+
+// Close stops all the limiters.
+func (k *KeyedLimiter) Close() {
+	k.lock.Lock()
+	k.closed = true
+	k.lock.Unlock()
+
+	k.limiters.Close()
+}
+
+// get returns the limiter of key, creating it if missing, and postpones its eviction.
+func (k *KeyedLimiter) get(key string) *keyedEntry {
+	k.lock.RLock()
+	if e, ok := k.lookup(key); ok {
+		k.limiters.Set(key, e, k.idle)
+		k.lock.RUnlock()
+		return e
+	}
+	k.lock.RUnlock()
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	// the limiter may be created meanwhile.
+	if e, ok := k.lookup(key); ok {
+		k.limiters.Set(key, e, k.idle)
+		return e
+	}
+
+	limiter, stop := k.template(key, k.Limit(key))
+	e := &keyedEntry{
+		limiter: limiter,
+		stop:    stop,
+	}
+	k.limiters.Set(key, e, k.idle)
+	return e
+}
+
+func (k *KeyedLimiter) lookup(key string) (*keyedEntry, bool) {
+	v, ok := k.limiters.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return v.(*keyedEntry), true
+}
+
+// evict stops the limiter of key idle for the idle timeout, or keeps it for
+// another idle timeout if some of its events are still counted.
+func (k *KeyedLimiter) evict(key string, value lang.AnyType) {
+	e := value.(*keyedEntry)
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if curr, ok := k.lookup(key); ok {
+		// the limiter was used again meanwhile.
+		if curr != e {
+			e.close()
+		}
+		return
+	}
+	if !k.closed && e.limiter.Usage().Used > 0 {
+		k.limiters.Set(key, e, k.idle)
+		return
+	}
+	e.close()
+}
+
+// close stops the limiter once, an evicted entry may still be in use for a while.
+func (e *keyedEntry) close() {
+	e.once.Do(func() {
+		if e.stop != nil {
+			e.stop()
+		}
+	})
+}
+
+// SlidingWindowTemplate returns a Template of sliding window limiters of size,
+// newWindow creates the current window of each key, a LocalWindow if nil.
+func SlidingWindowTemplate(size time.Duration, newWindow NewKeyWindow) Template {
+	if newWindow == nil {
+		newWindow = func(string) (Window, StopFunc) {
+			return NewLocalWindow()
+		}
+	}
+
+	return func(key string, limit int64) (KeyLimiter, StopFunc) {
+		lim, stop := NewLimiter(size, limit, func() (Window, StopFunc) {
+			return newWindow(key)
+		})
+		return slidingKeyLimiter{lim}, stop
+	}
+}
+
+// TokenBucketTemplate returns a Template of token buckets refilled by limit tokens
+// per period, of burst limit.
+func TokenBucketTemplate(per time.Duration) Template {
+	return func(_ string, limit int64) (KeyLimiter, StopFunc) {
+		b := NewTokenBucket(float64(limit)/per.Seconds(), int(limit))
+		return bucketKeyLimiter{b, per}, nil
+	}
+}
+
+// WindowLimitTemplate returns a Template of WindowLimit of period seconds.
+func WindowLimitTemplate(period int64) Template {
+	return func(_ string, limit int64) (KeyLimiter, StopFunc) {
+		wl := NewWindowLimit(WithLimit(uint64(limit)), WithPeriod(period))
+		return windowKeyLimiter{wl}, nil
+	}
+}
+
+// Usage takes the end of the current window as the reset, though the events of
+// the previous window still weigh a little after it.
+func (l slidingKeyLimiter) Usage() Usage {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.advance(now)
+	return l.usage(now)
+}
+
+func (l slidingKeyLimiter) AllowUsage() (bool, Usage) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.advance(now)
+	// Trigger the possible sync behaviour, as AllowN.
+	defer l.curr.Sync(now)
+
+	ok := l.count(now)+1 <= l.limit
+	if ok {
+		l.curr.AddCount(1)
+	}
+	return ok, l.usage(now)
+}
+
+// usage is Usage at now, with l.mu held and the windows advanced.
+func (l slidingKeyLimiter) usage(now time.Time) Usage {
+	u := Usage{
+		Used:  l.count(now),
+		Limit: l.limit,
+		Reset: l.curr.Start().Add(l.size).Sub(now),
+	}
+	if u.Used >= u.Limit {
+		u.RetryAfter = u.Reset
+	}
+	return u
+}
+
+// SetLimit keeps the tokens taken, as the windows keep the events counted.
+func (l bucketKeyLimiter) SetLimit(limit int64) {
+	b := l.TokenBucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	tokens := b.advance(now) + float64(int(limit)-b.burst)
+	b.tokens = math.Min(tokens, float64(limit))
+	if now.After(b.last) {
+		b.last = now
+	}
+	b.rate = float64(limit) / l.per.Seconds()
+	b.burst = int(limit)
+}
+
+func (l bucketKeyLimiter) Usage() Usage {
+	b := l.TokenBucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return l.usage(b.now())
+}
+
+func (l bucketKeyLimiter) AllowUsage() (bool, Usage) {
+	b := l.TokenBucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	ok := b.reserve(now, 1, 0).ok
+	return ok, l.usage(now)
+}
+
+// usage is Usage at now, with b.mu held.
+func (l bucketKeyLimiter) usage(now time.Time) Usage {
+	b := l.TokenBucket
+	tokens := b.advance(now)
+	return Usage{
+		Used:       int64(math.Ceil(float64(b.burst) - tokens)),
+		Limit:      int64(b.burst),
+		Reset:      durationFromTokens(float64(b.burst)-tokens, b.rate),
+		RetryAfter: durationFromTokens(math.Max(0, 1-tokens), b.rate),
+	}
+}
+
+func (l windowKeyLimiter) Allow() bool {
+	return l.Access()
+}
+
+func (l windowKeyLimiter) SetLimit(limit int64) {
+	l.WindowLimit.SetLimit(uint64(limit))
+}
+
+func (l windowKeyLimiter) Usage() Usage {
+	now := timex.NowMs()
+	wl := l.WindowLimit
+	wl.lock.RLock()
+	defer wl.lock.RUnlock()
+	return l.usage(now)
+}
+
+func (l windowKeyLimiter) AllowUsage() (bool, Usage) {
+	now := timex.NowMs()
+	wl := l.WindowLimit
+	wl.lock.Lock()
+	defer wl.lock.Unlock()
+
+	ok := wl.access(now)
+	return ok, l.usage(now)
+}
+
+// usage is Usage at now in ms, with wl.lock held.
+func (l windowKeyLimiter) usage(now int64) Usage {
+	wl := l.WindowLimit
+	// the accesses of the period, the oldest first.
+	var win []int64
+	for k, v := range wl.win {
+		if now-v < wl.period {
+			win = wl.win[k:]
+			break
+		}
+	}
+
+	u := Usage{
+		Used:  int64(len(win)),
+		Limit: int64(wl.limit),
+	}
+	if len(win) > 0 {
+		u.Reset = time.Duration(win[len(win)-1]+wl.period-now) * time.Millisecond
+		if uint64(len(win)) >= wl.limit {
+			u.RetryAfter = time.Duration(win[0]+wl.period-now) * time.Millisecond
+		}
+	}
+	return u
+}
@@ -0,0 +1,190 @@
+package limit
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	templates := map[string]Template{
+		"sliding": SlidingWindowTemplate(time.Minute, nil),
+		"bucket":  TokenBucketTemplate(time.Minute),
+		"window":  WindowLimitTemplate(60),
+	}
+	for name, template := range templates {
+		k := NewKeyedLimiter(template, 3)
+
+		for i := 0; i < 3; i++ {
+			assert.True(t, k.Allow("a"), name)
+		}
+		assert.False(t, k.Allow("a"), name)
+		assert.True(t, k.Allow("b"), name)
+		assert.Equal(t, 2, k.Len())
+
+		usage, ok := k.Usage("a")
+		assert.True(t, ok)
+		assert.Equal(t, int64(3), usage.Used, name)
+		assert.Equal(t, int64(3), usage.Limit, name)
+		assert.True(t, usage.RetryAfter > 0 && usage.RetryAfter <= usage.Reset, name)
+		assert.True(t, usage.Reset <= time.Minute, name)
+
+		usages := k.Usages()
+		assert.Len(t, usages, 2, name)
+		assert.Equal(t, int64(1), usages["b"].Used, name)
+		assert.Equal(t, time.Duration(0), usages["b"].RetryAfter, name)
+		_, ok = k.Usage("c")
+		assert.False(t, ok)
+
+		// the override applies to the existing limiter and the new ones.
+		k.SetLimit("a", 5)
+		k.SetLimit("c", 1)
+		assert.Equal(t, int64(5), k.Limit("a"))
+		assert.True(t, k.Allow("a"), name)
+		assert.True(t, k.Allow("c"), name)
+		assert.False(t, k.Allow("c"), name)
+
+		k.RemoveLimit("a")
+		assert.Equal(t, int64(3), k.Limit("a"))
+		assert.False(t, k.Allow("a"), name)
+		usage, _ = k.Usage("a")
+		assert.Equal(t, int64(3), usage.Limit, name)
+
+		// the usage is made at once with the decision.
+		for i := int64(1); i <= 3; i++ {
+			ok, usage := k.AllowUsage("d")
+			assert.True(t, ok, name)
+			assert.Equal(t, i, usage.Used, name)
+			assert.Equal(t, int64(3), usage.Limit, name)
+		}
+		ok, usage = k.AllowUsage("d")
+		assert.False(t, ok, name)
+		assert.Equal(t, int64(3), usage.Used, name)
+		assert.True(t, usage.RetryAfter > 0, name)
+
+		k.Close()
+		assert.Equal(t, 0, k.Len())
+	}
+}
+
+func TestKeyedLimiterSetLimitCreating(t *testing.T) {
+	creating := make(chan struct{})
+	template := SlidingWindowTemplate(time.Minute, nil)
+	k := NewKeyedLimiter(func(key string, limit int64) (KeyLimiter, StopFunc) {
+		close(creating)
+		// lets SetLimit run while the limiter is created with the old limit.
+		time.Sleep(50 * time.Millisecond)
+		return template(key, limit)
+	}, 3)
+	defer k.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		k.Allow("a")
+	}()
+	<-creating
+	k.SetLimit("a", 5)
+	<-done
+
+	usage, ok := k.Usage("a")
+	assert.True(t, ok)
+	assert.Equal(t, int64(5), usage.Limit)
+}
+
+func TestKeyedLimiterIdle(t *testing.T) {
+	stopped := make(chan string, 10)
+	clock := newFakeClock()
+	template := func(_ string, limit int64) (KeyLimiter, StopFunc) {
+		b := NewTokenBucket(float64(limit)/60, int(limit), WithTokenBucketClock(clock.Now))
+		return bucketKeyLimiter{b, time.Minute}, func() { stopped <- "stopped" }
+	}
+
+	k := NewKeyedLimiter(template, 1, WithIdleTimeout(50*time.Millisecond))
+	defer k.Close()
+
+	assert.True(t, k.Allow("a"))
+	assert.False(t, k.Allow("a"))
+
+	// the events postpone the eviction.
+	for i := 0; i < 5; i++ {
+		time.Sleep(20 * time.Millisecond)
+		assert.False(t, k.Allow("a"))
+	}
+	assert.Equal(t, 1, k.Len())
+
+	// the period is longer than the idle timeout, the key is kept as long as
+	// its event is counted, so it doesn't start over early.
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, 1, k.Len())
+	assert.False(t, k.Allow("a"))
+
+	// the idle key is evicted and stopped once its event is no longer counted,
+	// then starts over.
+	clock.Advance(time.Minute)
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, 0, k.Len())
+	assert.Equal(t, "stopped", <-stopped)
+	assert.True(t, k.Allow("a"))
+}
+
+func TestKeyedLimiterSyncWindow(t *testing.T) {
+	server := NewCounterServer()
+	template := SlidingWindowTemplate(time.Hour, func(key string) (Window, StopFunc) {
+		return NewSyncWindow(key, NewBatchSynchronizer(server, WithSyncInterval(10*time.Millisecond)))
+	})
+
+	// two limiters as in two processes.
+	k1 := NewKeyedLimiter(template, 10)
+	defer k1.Close()
+	k2 := NewKeyedLimiter(template, 10)
+	defer k2.Close()
+
+	for i := 0; i < 4; i++ {
+		assert.True(t, k1.Allow("a"))
+	}
+	for i := 0; i < 3; i++ {
+		assert.True(t, k2.Allow("a"))
+	}
+	assert.True(t, k2.Allow("b"))
+
+	// the syncs are driven by the events, AllowN of 0 only syncs.
+	count := func(k *KeyedLimiter, key string) int64 {
+		lim := k.get(key).limiter.(slidingKeyLimiter)
+		lim.AllowN(time.Now(), 0)
+		return lim.Count(time.Now())
+	}
+
+	// each key is synced on its own.
+	assert.Eventually(t, func() bool {
+		a1, a2, b1, b2 := count(k1, "a"), count(k2, "a"), count(k1, "b"), count(k2, "b")
+		return a1 == 7 && a2 == 7 && b1 == 1 && b2 == 1
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestKeyedLimiterConcurrent(t *testing.T) {
+	k := NewKeyedLimiter(TokenBucketTemplate(time.Hour), 1000)
+	defer k.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.True(t, k.Allow(strconv.Itoa(j%10)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	var used int64
+	for _, usage := range k.Usages() {
+		used += usage.Used
+	}
+	assert.Equal(t, 10, k.Len())
+	assert.Equal(t, int64(800), used)
+}
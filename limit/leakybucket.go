@@ -0,0 +1,164 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 04:05:37
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 04:05:37
+ * @Description: 漏桶限流
+ */
+package limit
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull = errors.New("limit: too many callers waiting for the leaky bucket")
+	ErrLeakyRate = errors.New("limit: the leaky bucket rate must be positive and at most one event per nanosecond")
+)
+
+type (
+	// LeakyBucket spaces the events evenly at rate events per period, each
+	// Take blocks until its own slot. It's concurrent safe.
+	//
+	// The slack lets the slots left unused for a while be taken at once, so
+	// that a short burst after an idle time isn't slowed down.
+	LeakyBucket struct {
+		mu         sync.Mutex
+		interval   time.Duration
+		maxSlack   time.Duration
+		maxPending int
+		pending    int
+		// the slot taken last.
+		last  time.Time
+		now   func() time.Time
+		after func(d time.Duration) <-chan time.Time
+	}
+
+	// LeakyBucketOption customizes a LeakyBucket.
+	LeakyBucketOption func(*leakyBucketConfig)
+
+	leakyBucketConfig struct {
+		per        time.Duration
+		slack      int
+		maxPending int
+		now        func() time.Time
+		after      func(d time.Duration) <-chan time.Time
+	}
+)
+
+// WithPer sets the period of the rate, 1 second by default.
+func WithPer(per time.Duration) LeakyBucketOption {
+	return func(c *leakyBucketConfig) {
+		c.per = per
+	}
+}
+
+// WithSlack sets the number of unused slots which can be taken at once, 0 by default.
+func WithSlack(slack int) LeakyBucketOption {
+	return func(c *leakyBucketConfig) {
+		c.slack = slack
+	}
+}
+
+// WithMaxPending sets the number of callers allowed to wait for their slot,
+// Take fails with ErrQueueFull beyond. 0 means no limit, the default.
+func WithMaxPending(n int) LeakyBucketOption {
+	return func(c *leakyBucketConfig) {
+		c.maxPending = n
+	}
+}
+
+// WithLeakyBucketClock sets the function returning the current time, it's meant for tests.
+func WithLeakyBucketClock(now func() time.Time) LeakyBucketOption {
+	return func(c *leakyBucketConfig) {
+		c.now = now
+	}
+}
+
+// WithLeakyBucketTimer sets the function returning a channel receiving the time
+// once d is elapsed, as time.After, Take waits for it. With WithLeakyBucketClock,
+// it's meant for tests with a fake clock.
+func WithLeakyBucketTimer(after func(d time.Duration) <-chan time.Time) LeakyBucketOption {
+	return func(c *leakyBucketConfig) {
+		c.after = after
+	}
+}
+
+// NewLeakyBucket returns a LeakyBucket allowing rate events per period, it fails
+// with ErrLeakyRate if rate isn't positive or the period can't be split by rate.
+func NewLeakyBucket(rate int, opts ...LeakyBucketOption) (*LeakyBucket, error) {
+	c := leakyBucketConfig{
+		per:   time.Second,
+		now:   time.Now,
+		after: time.After,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	if rate <= 0 {
+		return nil, ErrLeakyRate
+	}
+	interval := c.per / time.Duration(rate)
+	if interval <= 0 {
+		return nil, ErrLeakyRate
+	}
+
+	return &LeakyBucket{
+		interval:   interval,
+		maxSlack:   time.Duration(c.slack) * interval,
+		maxPending: c.maxPending,
+		now:        c.now,
+		after:      c.after,
+	}, nil
+}
+
+// Take blocks until the next slot and returns its time, or fails at once with
+// ErrQueueFull if too many callers are waiting.
+func (b *LeakyBucket) Take() (time.Time, error) {
+	b.mu.Lock()
+
+	now := b.now()
+	if b.last.IsZero() {
+		b.last = now
+		b.mu.Unlock()
+		return now, nil
+	}
+
+	slot := b.last.Add(b.interval)
+	// the slots older than the slack are lost.
+	if earliest := now.Add(-b.maxSlack); slot.Before(earliest) {
+		slot = earliest
+	}
+
+	wait := slot.Sub(now)
+	if wait <= 0 {
+		b.last = slot
+		b.mu.Unlock()
+		return now, nil
+	}
+
+	if b.maxPending > 0 && b.pending >= b.maxPending {
+		b.mu.Unlock()
+		return time.Time{}, ErrQueueFull
+	}
+	b.last = slot
+	b.pending++
+	b.mu.Unlock()
+
+	<-b.after(wait)
+
+	b.mu.Lock()
+	b.pending--
+	b.mu.Unlock()
+	return slot, nil
+}
+
+// Pending returns the number of callers waiting for their slot.
+func (b *LeakyBucket) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pending
+}
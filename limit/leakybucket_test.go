@@ -0,0 +1,127 @@
+package limit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakeLeakyBucket returns a LeakyBucket on clock, waiting advances clock.
+func newFakeLeakyBucket(t *testing.T, clock *fakeClock, rate int, opts ...LeakyBucketOption) *LeakyBucket {
+	opts = append([]LeakyBucketOption{WithLeakyBucketClock(clock.Now), WithLeakyBucketTimer(clock.After)}, opts...)
+	b, err := NewLeakyBucket(rate, opts...)
+	assert.Nil(t, err)
+	return b
+}
+
+func TestLeakyBucketTake(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	b := newFakeLeakyBucket(t, clock, 10)
+
+	// the slots are 100ms apart.
+	for i := 0; i < 5; i++ {
+		slot, err := b.Take()
+		assert.Nil(t, err)
+		assert.Equal(t, start.Add(time.Duration(i)*100*time.Millisecond), slot)
+	}
+	assert.Equal(t, start.Add(400*time.Millisecond), clock.Now())
+
+	// without slack, an idle time is not made up for.
+	clock.Advance(time.Second)
+	now := clock.Now()
+	slot, _ := b.Take()
+	assert.Equal(t, now, slot)
+	slot, _ = b.Take()
+	assert.Equal(t, now.Add(100*time.Millisecond), slot)
+}
+
+func TestLeakyBucketSlack(t *testing.T) {
+	clock := newFakeClock()
+	b := newFakeLeakyBucket(t, clock, 10, WithPer(time.Second), WithSlack(3))
+	_, err := b.Take()
+	assert.Nil(t, err)
+
+	// 3 slots unused for the idle time are taken at once.
+	clock.Advance(time.Second)
+	now := clock.Now()
+	for i := 0; i < 4; i++ {
+		_, err = b.Take()
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, now, clock.Now())
+
+	_, err = b.Take()
+	assert.Nil(t, err)
+	assert.Equal(t, now.Add(100*time.Millisecond), clock.Now())
+}
+
+func TestLeakyBucketMaxPending(t *testing.T) {
+	clock := newFakeClock()
+	// the waiters are blocked until released.
+	release := make(chan time.Time)
+	b := newFakeLeakyBucket(t, clock, 1, WithMaxPending(2), WithLeakyBucketTimer(func(time.Duration) <-chan time.Time {
+		return release
+	}))
+	_, err := b.Take()
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	slots := make(chan time.Time, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slot, err := b.Take()
+			assert.Nil(t, err)
+			slots <- slot
+		}()
+	}
+
+	for b.Pending() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	_, err = b.Take()
+	assert.Equal(t, ErrQueueFull, err)
+
+	close(release)
+	wg.Wait()
+	close(slots)
+	assert.Equal(t, 0, b.Pending())
+
+	start := clock.Now()
+	seen := make(map[time.Time]bool)
+	for slot := range slots {
+		seen[slot] = true
+	}
+	assert.Equal(t, map[time.Time]bool{start.Add(time.Second): true, start.Add(2 * time.Second): true}, seen)
+}
+
+func TestLeakyBucketRealClock(t *testing.T) {
+	b, err := NewLeakyBucket(200)
+	assert.Nil(t, err)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err := b.Take()
+		assert.Nil(t, err)
+	}
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+}
+
+func TestLeakyBucketRate(t *testing.T) {
+	_, err := NewLeakyBucket(0)
+	assert.Equal(t, ErrLeakyRate, err)
+	_, err = NewLeakyBucket(-1)
+	assert.Equal(t, ErrLeakyRate, err)
+	// less than a nanosecond between the events.
+	_, err = NewLeakyBucket(10, WithPer(time.Nanosecond))
+	assert.Equal(t, ErrLeakyRate, err)
+
+	clock := newFakeClock()
+	b := newFakeLeakyBucket(t, clock, 1, WithPer(time.Nanosecond))
+	first, _ := b.Take()
+	second, _ := b.Take()
+	assert.Equal(t, time.Nanosecond, second.Sub(first))
+}
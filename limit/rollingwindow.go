@@ -67,6 +67,14 @@ func (wl *WindowLimit) Access() bool {
 
 	wl.lock.Lock()
 	defer wl.lock.Unlock()
+	return wl.access(now)
+}
+
+// access is Access at now, with wl.lock held.
+func (wl *WindowLimit) access(now int64) bool {
+	if wl.limit == 0 {
+		return false
+	}
 
 	if uint64(len(wl.win)) < wl.limit {
 		wl.win = append(wl.win, now)
@@ -83,12 +91,31 @@ func (wl *WindowLimit) Access() bool {
 	return true
 }
 
+// Limit returns the limit in a period.
+func (wl *WindowLimit) Limit() uint64 {
+	wl.lock.RLock()
+	defer wl.lock.RUnlock()
+	return wl.limit
+}
+
+// SetLimit sets a new limit in a period.
+func (wl *WindowLimit) SetLimit(cnt uint64) {
+	wl.lock.Lock()
+	defer wl.lock.Unlock()
+
+	wl.limit = cnt
+	// drop the oldest accesses beyond the new limit.
+	if n := uint64(len(wl.win)); n > cnt {
+		wl.win = wl.win[n-cnt:]
+	}
+}
+
 func (wl *WindowLimit) Count() uint64 {
 	now := timex.NowMs()
 	wl.lock.RLock()
 	defer wl.lock.RUnlock()
 
-	idx := uint64(0)
+	idx := uint64(len(wl.win))
 	for k, v := range wl.win {
 		if now-v < wl.period {
 			idx = uint64(k)
@@ -6,6 +6,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestWindowLimit(t *testing.T) {
@@ -31,6 +33,17 @@ func TestWindowLimit(t *testing.T) {
 	fmt.Println("total:", total, " succ:", succ)
 }
 
+func TestWindowLimitCountExpired(t *testing.T) {
+	wl := NewWindowLimit(WithLimit(2), WithPeriod(1))
+	assert.True(t, wl.Access())
+	assert.True(t, wl.Access())
+	assert.Equal(t, uint64(2), wl.Count())
+
+	// none of the accesses is in the period any longer.
+	time.Sleep(1100 * time.Millisecond)
+	assert.Equal(t, uint64(0), wl.Count())
+}
+
 func TestMultWindowLimit(t *testing.T) {
 	keys := [...]string{"10.0.2.93", "10.0.2.113"}
 	var sm sync.Map
@@ -94,10 +94,7 @@ func (lim *Limiter) AllowN(now time.Time, n int64) bool {
 	defer lim.mu.Unlock()
 
 	lim.advance(now)
-
-	elapsed := now.Sub(lim.curr.Start())
-	weight := float64(lim.size-elapsed) / float64(lim.size)
-	count := int64(weight*float64(lim.prev.Count())) + lim.curr.Count()
+	count := lim.count(now)
 
 	// Trigger the possible sync behaviour.
 	defer lim.curr.Sync(now)
@@ -110,6 +107,22 @@ func (lim *Limiter) AllowN(now time.Time, n int64) bool {
 	return true
 }
 
+// Count returns the events counted at time now, the ones of the previous window
+// are weighted by its overlap with the sliding window.
+func (lim *Limiter) Count(now time.Time) int64 {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	lim.advance(now)
+	return lim.count(now)
+}
+
+func (lim *Limiter) count(now time.Time) int64 {
+	elapsed := now.Sub(lim.curr.Start())
+	weight := float64(lim.size-elapsed) / float64(lim.size)
+	return int64(weight*float64(lim.prev.Count())) + lim.curr.Count()
+}
+
 // advance updates the current/previous windows resulting from the passage of time.
 func (lim *Limiter) advance(now time.Time) {
 	// Calculate the start boundary of the expected current-window.
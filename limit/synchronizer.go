@@ -0,0 +1,265 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 04:56:14
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 04:56:14
+ * @Description: 分布式滑动窗口的计数同步
+ */
+package limit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cnzf1/gocore/thread"
+)
+
+const (
+	defaultSyncInterval = 100 * time.Millisecond
+	defaultBatchSize    = 100
+	// the state of a window idle for longer is dropped.
+	syncStateIdle = time.Minute
+)
+
+type (
+	// CounterDelta adds Delta to the count of the window of Key starting at
+	// Start, in nanoseconds.
+	CounterDelta struct {
+		Key   string `json:"key"`
+		Start int64  `json:"start"`
+		Delta int64  `json:"delta"`
+	}
+
+	// CounterClient adds the changes of the windows to a counter server, and
+	// returns their counts across all the processes, in the order of deltas.
+	CounterClient interface {
+		Add(deltas []CounterDelta) ([]int64, error)
+	}
+
+	// BatchSynchronizer is a Synchronizer shared by the SyncWindows of a process,
+	// it sends their changes to a counter server in batches, at most once per
+	// interval for each window, and hands the changes of the other processes
+	// back to each window on its next Sync.
+	//
+	// It's started by the first SyncWindow and stopped by the last one.
+	BatchSynchronizer struct {
+		client    CounterClient
+		interval  time.Duration
+		batchSize int
+
+		lock    sync.Mutex
+		states  map[string]*syncState
+		queue   []SyncRequest
+		running int
+		flush   chan struct{}
+		stop    chan struct{}
+		done    chan struct{}
+	}
+
+	// SyncOption customizes a BatchSynchronizer.
+	SyncOption func(*BatchSynchronizer)
+
+	syncState struct {
+		lastSync time.Time
+		inflight bool
+		resp     *SyncResponse
+	}
+)
+
+// WithSyncInterval sets the least interval between two syncs of a window, 100ms by default.
+func WithSyncInterval(interval time.Duration) SyncOption {
+	return func(s *BatchSynchronizer) {
+		s.interval = interval
+	}
+}
+
+// WithBatchSize sets the number of requests which makes a batch sent at once,
+// without waiting for the interval, 100 by default.
+func WithBatchSize(size int) SyncOption {
+	return func(s *BatchSynchronizer) {
+		s.batchSize = size
+	}
+}
+
+// NewBatchSynchronizer returns a BatchSynchronizer sending the changes with client.
+func NewBatchSynchronizer(client CounterClient, opts ...SyncOption) *BatchSynchronizer {
+	s := &BatchSynchronizer{
+		client:    client,
+		interval:  defaultSyncInterval,
+		batchSize: defaultBatchSize,
+		states:    make(map[string]*syncState),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Start starts the goroutine sending the batches, if not started yet.
+func (s *BatchSynchronizer) Start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.running++
+	if s.running > 1 {
+		return
+	}
+
+	s.flush = make(chan struct{}, 1)
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	flush, stop, done := s.flush, s.stop, s.done
+	thread.GoSafe(func() {
+		s.run(flush, stop, done)
+	})
+}
+
+// Stop stops the goroutine and waits for it to exit, once called as many times as Start.
+func (s *BatchSynchronizer) Stop() {
+	s.lock.Lock()
+	if s.running == 0 {
+		s.lock.Unlock()
+		return
+	}
+
+	s.running--
+	if s.running > 0 {
+		s.lock.Unlock()
+		return
+	}
+
+	stop, done := s.stop, s.done
+	s.lock.Unlock()
+
+	close(stop)
+	<-done
+
+	// the requests never sent are queued again by the next syncs.
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, req := range s.queue {
+		if state, ok := s.states[req.Key]; ok {
+			state.inflight = false
+		}
+	}
+	s.queue = nil
+}
+
+// Sync hands the pending response of the window to handleResp, and queues a
+// new request of the window if its interval is elapsed and no request is in
+// flight. It never blocks on the counter server.
+func (s *BatchSynchronizer) Sync(now time.Time, makeReq MakeFunc, handleResp HandleFunc) {
+	req := makeReq()
+
+	s.lock.Lock()
+	state, ok := s.states[req.Key]
+	if !ok {
+		state = &syncState{}
+		s.states[req.Key] = state
+	}
+
+	if resp := state.resp; resp != nil {
+		state.resp = nil
+		s.lock.Unlock()
+
+		handleResp(*resp)
+		// the request must carry the changes left after the response.
+		req = makeReq()
+		s.lock.Lock()
+	}
+	defer s.lock.Unlock()
+
+	if state.inflight || now.Sub(state.lastSync) < s.interval || s.running == 0 {
+		return
+	}
+
+	state.inflight = true
+	state.lastSync = now
+	s.queue = append(s.queue, req)
+	if len(s.queue) >= s.batchSize {
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *BatchSynchronizer) run(flush, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.send()
+			s.cleanup()
+		case <-flush:
+			s.send()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// send sends the queued requests, and keeps the responses for the windows.
+func (s *BatchSynchronizer) send() {
+	s.lock.Lock()
+	reqs := s.queue
+	s.queue = nil
+	s.lock.Unlock()
+
+	for len(reqs) > 0 {
+		n := len(reqs)
+		if n > s.batchSize {
+			n = s.batchSize
+		}
+		s.sendBatch(reqs[:n])
+		reqs = reqs[n:]
+	}
+}
+
+func (s *BatchSynchronizer) sendBatch(reqs []SyncRequest) {
+	deltas := make([]CounterDelta, len(reqs))
+	for i, req := range reqs {
+		deltas[i] = CounterDelta{
+			Key:   req.Key,
+			Start: req.Start,
+			Delta: req.Changes,
+		}
+	}
+
+	counts, err := s.client.Add(deltas)
+	ok := err == nil && len(counts) == len(reqs)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, req := range reqs {
+		resp := &SyncResponse{
+			OK:    ok,
+			Start: req.Start,
+		}
+		if ok {
+			resp.Changes = req.Changes
+			resp.OtherChanges = counts[i] - req.Count
+		}
+
+		if state, exists := s.states[req.Key]; exists {
+			state.inflight = false
+			state.resp = resp
+		}
+	}
+}
+
+// cleanup drops the states of the windows not synced for a while.
+func (s *BatchSynchronizer) cleanup() {
+	now := time.Now()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for key, state := range s.states {
+		if !state.inflight && state.resp == nil && now.Sub(state.lastSync) > syncStateIdle {
+			delete(s.states, key)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package limit
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterServer(t *testing.T) {
+	s := NewCounterServer()
+
+	counts, err := s.Add([]CounterDelta{{"a", 10, 2}, {"a", 10, 3}, {"b", 10, 1}})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{2, 5, 1}, counts)
+
+	// a delta of 0 reads the count, the two latest windows are kept.
+	counts, _ = s.Add([]CounterDelta{{"a", 20, 1}, {"a", 10, 0}, {"a", 30, 4}, {"a", 10, 0}, {"a", 20, 0}})
+	assert.Equal(t, []int64{1, 5, 4, 0, 1}, counts)
+}
+
+func TestCounterServerIdle(t *testing.T) {
+	clock := newFakeClock()
+	s := NewCounterServer(WithCounterIdleTimeout(time.Minute))
+	s.now = clock.Now
+
+	s.Add([]CounterDelta{{"a", 10, 1}, {"b", 10, 1}})
+	clock.Advance(40 * time.Second)
+	s.Add([]CounterDelta{{"a", 10, 1}})
+	clock.Advance(40 * time.Second)
+
+	// b is idle for 80s, a for 40s only.
+	counts, _ := s.Add([]CounterDelta{{"c", 10, 1}, {"a", 10, 0}, {"b", 10, 0}})
+	assert.Equal(t, []int64{1, 2, 0}, counts)
+	assert.Equal(t, 3, s.Len())
+	clock.Advance(2 * time.Minute)
+	s.Add(nil)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestCounterServerLimits(t *testing.T) {
+	s := NewCounterServer(WithMaxRequestSize(64), WithConnTimeout(50*time.Millisecond))
+	large := `[` + strings.Repeat(`{"key":"a","start":10,"delta":1},`, 10) + `{}]`
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(large))
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+	go s.Serve(ln)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(large + "\n"))
+	assert.Nil(t, err)
+	_, err = decodeCounterResponse(json.NewDecoder(conn))
+	assert.Equal(t, ErrCounterServer, err)
+
+	// the idle connection is closed by the server.
+	conn, err = net.Dial("tcp", ln.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestBatchSynchronizerHTTP(t *testing.T) {
+	server := httptest.NewServer(NewCounterServer())
+	defer server.Close()
+
+	testSyncLimiters(t, func() CounterClient {
+		return NewHTTPCounterClient(server.URL, nil)
+	})
+}
+
+func TestHTTPCounterClientTimeout(t *testing.T) {
+	hang := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer server.Close()
+	defer close(hang)
+
+	c := NewHTTPCounterClient(server.URL, nil)
+	assert.Equal(t, defaultCounterClientTimeout, c.client.Timeout)
+
+	// a hung server fails the round trip, instead of blocking it.
+	c.client.Timeout = 50 * time.Millisecond
+	_, err := c.Add([]CounterDelta{{Key: "key", Start: 1, Delta: 1}})
+	assert.NotNil(t, err)
+}
+
+func TestBatchSynchronizerTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	s := NewCounterServer()
+	go s.Serve(ln)
+
+	testSyncLimiters(t, func() CounterClient {
+		c := NewTCPCounterClient(ln.Addr().String(), time.Second)
+		t.Cleanup(func() { c.Close() })
+		return c
+	})
+}
+
+// testSyncLimiters runs two limiters of a key, each with its own synchronizer
+// as in two processes, and checks they count the events of each other.
+func testSyncLimiters(t *testing.T, newClient func() CounterClient) {
+	newLimiter := func() (*Limiter, StopFunc) {
+		syncer := NewBatchSynchronizer(newClient(), WithSyncInterval(10*time.Millisecond))
+		return NewLimiter(time.Hour, 10, func() (Window, StopFunc) {
+			return NewSyncWindow("key", syncer)
+		})
+	}
+
+	lim1, stop1 := newLimiter()
+	defer stop1()
+	lim2, stop2 := newLimiter()
+	defer stop2()
+
+	for i := 0; i < 4; i++ {
+		assert.True(t, lim1.Allow())
+	}
+	for i := 0; i < 3; i++ {
+		assert.True(t, lim2.Allow())
+	}
+
+	// the syncs are driven by the events, AllowN of 0 only syncs.
+	synced := func(count int64) bool {
+		lim1.AllowN(time.Now(), 0)
+		lim2.AllowN(time.Now(), 0)
+		return lim1.Count(time.Now()) == count && lim2.Count(time.Now()) == count
+	}
+
+	// the counts converge once the changes are synced both ways.
+	assert.Eventually(t, func() bool {
+		return synced(7)
+	}, 2*time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, lim1.Allow())
+	}
+	assert.Eventually(t, func() bool {
+		return synced(10)
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.False(t, lim2.Allow())
+}
@@ -0,0 +1,331 @@
+/*
+ * @Author: cnzf1
+ * @Date: 2026-10-18 03:42:08
+ * @LastEditors: cnzf1
+ * @LastEditTime: 2026-10-18 03:42:08
+ * @Description: 令牌桶限流
+ */
+package limit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// Inf is the infinite rate, a TokenBucket of rate Inf allows all the events.
+const Inf = math.MaxFloat64
+
+var (
+	ErrExceedsBurst        = errors.New("limit: n exceeds the burst of the token bucket")
+	ErrWouldExceedDeadline = errors.New("limit: wait would exceed the context deadline")
+)
+
+type (
+	// TokenBucket is a token bucket of burst tokens, refilled at rate tokens
+	// per second. An event takes one token, and waits for it if the bucket is
+	// empty. It's concurrent safe.
+	TokenBucket struct {
+		mu     sync.Mutex
+		rate   float64
+		burst  int
+		tokens float64
+		// the time tokens was last updated.
+		last  time.Time
+		now   func() time.Time
+		after func(d time.Duration) <-chan time.Time
+	}
+
+	// Reservation is the tokens reserved by TokenBucket.ReserveN, the events
+	// can happen once its delay is elapsed.
+	Reservation struct {
+		ok        bool
+		bucket    *TokenBucket
+		tokens    int
+		timeToAct time.Time
+	}
+
+	// TokenBucketOption customizes a TokenBucket.
+	TokenBucketOption func(*TokenBucket)
+)
+
+// WithTokenBucketClock sets the function returning the current time, it's meant for tests.
+func WithTokenBucketClock(now func() time.Time) TokenBucketOption {
+	return func(b *TokenBucket) {
+		b.now = now
+	}
+}
+
+// WithTokenBucketTimer sets the function returning a channel receiving the time
+// once d is elapsed, as time.After, it's meant for tests with a fake clock.
+func WithTokenBucketTimer(after func(d time.Duration) <-chan time.Time) TokenBucketOption {
+	return func(b *TokenBucket) {
+		b.after = after
+	}
+}
+
+// NewTokenBucket returns a full TokenBucket of burst tokens refilled at rate
+// tokens per second.
+func NewTokenBucket(rate float64, burst int, opts ...TokenBucketOption) *TokenBucket {
+	b := &TokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	b.last = b.now()
+	return b
+}
+
+// Rate returns the tokens refilled per second.
+func (b *TokenBucket) Rate() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rate
+}
+
+// Burst returns the size of the bucket.
+func (b *TokenBucket) Burst() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.burst
+}
+
+// Tokens returns the tokens available now, it's negative if the tokens of the
+// future are reserved.
+func (b *TokenBucket) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.advance(b.now())
+}
+
+// SetRate changes the rate, the tokens refilled so far are kept.
+func (b *TokenBucket) SetRate(rate float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = b.advance(now)
+	if now.After(b.last) {
+		b.last = now
+	}
+	b.rate = rate
+}
+
+// SetBurst changes the burst, the tokens beyond the new burst are dropped.
+func (b *TokenBucket) SetBurst(burst int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = math.Min(b.advance(now), float64(burst))
+	if now.After(b.last) {
+		b.last = now
+	}
+	b.burst = burst
+}
+
+// Allow is shorthand for AllowN(now, 1).
+func (b *TokenBucket) Allow() bool {
+	return b.AllowN(b.now(), 1)
+}
+
+// AllowN reports whether n events may happen at time now, and takes their
+// tokens if so.
+func (b *TokenBucket) AllowN(now time.Time, n int) bool {
+	return b.reserveN(now, n, 0).ok
+}
+
+// Reserve is shorthand for ReserveN(now, 1).
+func (b *TokenBucket) Reserve() *Reservation {
+	return b.ReserveN(b.now(), 1)
+}
+
+// ReserveN reserves the tokens of n events at time now, whether they are
+// available or not, and returns the Reservation telling how long to wait
+// before the events happen. The Reservation is not OK if n exceeds the burst.
+func (b *TokenBucket) ReserveN(now time.Time, n int) *Reservation {
+	return b.reserveN(now, n, math.MaxInt64)
+}
+
+// Wait is shorthand for WaitN(ctx, 1).
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	return b.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n events may happen, it fails at once if n exceeds the burst
+// or the wait would exceed the deadline of ctx, and returns the error of ctx if
+// it's done before. The tokens are given back when it fails.
+func (b *TokenBucket) WaitN(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := b.now()
+	maxWait := time.Duration(math.MaxInt64)
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = deadline.Sub(now)
+	}
+
+	r := b.reserveN(now, n, maxWait)
+	if !r.ok {
+		if n > b.Burst() && b.Rate() != Inf {
+			return ErrExceedsBurst
+		}
+		return ErrWouldExceedDeadline
+	}
+
+	delay := r.DelayFrom(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	var elapsed <-chan time.Time
+	if b.after != nil {
+		elapsed = b.after(delay)
+	} else {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		elapsed = timer.C
+	}
+
+	select {
+	case <-elapsed:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// OK reports whether the tokens are reserved, the events must not happen otherwise.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay is shorthand for DelayFrom(now).
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+	return r.DelayFrom(r.bucket.now())
+}
+
+// DelayFrom returns how long to wait from now before the events happen, or the
+// maximum duration if the Reservation is not OK.
+func (r *Reservation) DelayFrom(now time.Time) time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+
+	if delay := r.timeToAct.Sub(now); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// Cancel is shorthand for CancelAt(now).
+func (r *Reservation) Cancel() {
+	if r.ok {
+		r.CancelAt(r.bucket.now())
+	}
+}
+
+// CancelAt gives the tokens back to the bucket, if the events were not due
+// to happen before now.
+func (r *Reservation) CancelAt(now time.Time) {
+	if !r.ok || r.tokens == 0 || !r.timeToAct.After(now) {
+		return
+	}
+
+	b := r.bucket
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(b.advance(now)+float64(r.tokens), float64(b.burst))
+	if now.After(b.last) {
+		b.last = now
+	}
+	// cancelled only once.
+	r.tokens = 0
+}
+
+// reserveN takes the tokens of n events at time now, if the wait for them
+// doesn't exceed maxWait.
+func (b *TokenBucket) reserveN(now time.Time, n int, maxWait time.Duration) *Reservation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.reserve(now, n, maxWait)
+}
+
+// reserve is reserveN with b.mu held.
+func (b *TokenBucket) reserve(now time.Time, n int, maxWait time.Duration) *Reservation {
+	if b.rate == Inf {
+		return &Reservation{
+			ok:        true,
+			bucket:    b,
+			timeToAct: now,
+		}
+	}
+
+	r := &Reservation{
+		bucket: b,
+		tokens: n,
+	}
+	if n > b.burst {
+		return r
+	}
+
+	tokens := b.advance(now) - float64(n)
+	var wait time.Duration
+	if tokens < 0 {
+		if b.rate <= 0 {
+			return r
+		}
+		wait = durationFromTokens(-tokens, b.rate)
+	}
+	if wait > maxWait {
+		return r
+	}
+
+	b.tokens = tokens
+	if now.After(b.last) {
+		b.last = now
+	}
+
+	r.ok = true
+	r.timeToAct = now.Add(wait)
+	return r
+}
+
+// advance returns the tokens refilled until now, it doesn't update the bucket.
+func (b *TokenBucket) advance(now time.Time) float64 {
+	elapsed := now.Sub(b.last)
+	if elapsed <= 0 || b.rate <= 0 {
+		return b.tokens
+	}
+
+	// avoid overflowing on a long elapsed time.
+	missing := float64(b.burst) - b.tokens
+	if missing <= 0 {
+		return b.tokens
+	}
+	if elapsed >= durationFromTokens(missing, b.rate) {
+		return float64(b.burst)
+	}
+	return b.tokens + elapsed.Seconds()*b.rate
+}
+
+func durationFromTokens(tokens, rate float64) time.Duration {
+	seconds := tokens / rate
+	if seconds >= float64(math.MaxInt64)/float64(time.Second) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,164 @@
+package limit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	lock sync.Mutex
+	t    time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Unix(1700000000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func TestTokenBucketAllow(t *testing.T) {
+	clock := newFakeClock()
+	b := NewTokenBucket(10, 5, WithTokenBucketClock(clock.Now))
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, b.Allow())
+	}
+	assert.False(t, b.Allow())
+
+	// a token every 100ms.
+	clock.Advance(50 * time.Millisecond)
+	assert.False(t, b.Allow())
+	clock.Advance(50 * time.Millisecond)
+	assert.True(t, b.Allow())
+
+	// the bucket never holds more than burst tokens.
+	clock.Advance(time.Hour)
+	assert.Equal(t, float64(5), b.Tokens())
+	assert.False(t, b.AllowN(clock.Now(), 6))
+	assert.True(t, b.AllowN(clock.Now(), 5))
+	assert.False(t, b.AllowN(clock.Now(), 1))
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	clock := newFakeClock()
+	b := NewTokenBucket(10, 2, WithTokenBucketClock(clock.Now))
+
+	r := b.ReserveN(clock.Now(), 2)
+	assert.True(t, r.OK())
+	assert.Equal(t, time.Duration(0), r.Delay())
+
+	r1 := b.Reserve()
+	assert.True(t, r1.OK())
+	assert.Equal(t, 100*time.Millisecond, r1.Delay())
+	r2 := b.Reserve()
+	assert.Equal(t, 200*time.Millisecond, r2.Delay())
+	assert.Equal(t, float64(-2), b.Tokens())
+
+	// the tokens of r2 are given back, so the next reservation takes its place.
+	r2.Cancel()
+	r2.Cancel()
+	assert.Equal(t, float64(-1), b.Tokens())
+	r3 := b.Reserve()
+	assert.Equal(t, 200*time.Millisecond, r3.Delay())
+
+	// a reservation can not be cancelled once due.
+	clock.Advance(time.Second)
+	r1.Cancel()
+	assert.Equal(t, float64(2), b.Tokens())
+
+	assert.False(t, b.ReserveN(clock.Now(), 3).OK())
+	assert.Equal(t, time.Duration(1<<63-1), b.ReserveN(clock.Now(), 3).Delay())
+}
+
+func TestTokenBucketSetRateAndBurst(t *testing.T) {
+	clock := newFakeClock()
+	b := NewTokenBucket(1, 1, WithTokenBucketClock(clock.Now))
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+
+	b.SetRate(100)
+	b.SetBurst(10)
+	assert.Equal(t, float64(100), b.Rate())
+	assert.Equal(t, 10, b.Burst())
+
+	clock.Advance(50 * time.Millisecond)
+	assert.InDelta(t, 5, b.Tokens(), 1e-9)
+
+	b.SetBurst(2)
+	assert.Equal(t, float64(2), b.Tokens())
+
+	b.SetRate(0)
+	assert.True(t, b.AllowN(clock.Now(), 2))
+	assert.False(t, b.ReserveN(clock.Now(), 1).OK())
+
+	b.SetRate(Inf)
+	assert.True(t, b.AllowN(clock.Now(), 1000))
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	b := NewTokenBucket(100, 1)
+	ctx := context.Background()
+
+	start := time.Now()
+	assert.Nil(t, b.Wait(ctx))
+	assert.Nil(t, b.Wait(ctx))
+	assert.Nil(t, b.Wait(ctx))
+	assert.True(t, time.Since(start) >= 15*time.Millisecond)
+
+	assert.Equal(t, ErrExceedsBurst, b.WaitN(ctx, 2))
+
+	time.Sleep(20 * time.Millisecond)
+	timeout, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	assert.Nil(t, b.Wait(timeout))
+	assert.Equal(t, ErrWouldExceedDeadline, b.Wait(timeout))
+
+	// the tokens are given back when the context is cancelled.
+	b = NewTokenBucket(1, 1)
+	assert.True(t, b.Allow())
+	cancelled, cancel := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	assert.Equal(t, context.Canceled, b.Wait(cancelled))
+	assert.True(t, b.Tokens() > -0.5)
+	assert.Equal(t, context.Canceled, b.Wait(cancelled))
+}
+
+func TestTokenBucketWaitFakeClock(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	b := NewTokenBucket(1, 1, WithTokenBucketClock(clock.Now), WithTokenBucketTimer(clock.After))
+	ctx := context.Background()
+
+	// an hour of waits at a token per second, without any real wait.
+	started := time.Now()
+	for i := 0; i < 3600; i++ {
+		assert.Nil(t, b.Wait(ctx))
+	}
+	assert.Equal(t, start.Add(3599*time.Second), clock.Now())
+	assert.True(t, time.Since(started) < time.Second)
+}
+
+// After advances the clock by d at once, and returns a channel of the new time.
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.Advance(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}